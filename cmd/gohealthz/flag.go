@@ -5,20 +5,49 @@ import (
 	"fmt"
 	"os"
 	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
 )
 
 type config struct {
-	updaterInterval   time.Duration
-	httpClientTimeout time.Duration
+	updaterInterval        time.Duration
+	updaterConcurrency     int
+	updaterHostConcurrency int
+	failureThreshold       int
+	successThreshold       int
+	retries                int
+	retryBackoff           time.Duration
+	httpClientTimeout      time.Duration
+	databaseDriver         string
+	databasePath           string
+	historyLimit           int
+	historyRetention       time.Duration
+	webhookURL             string
+	webhookRetries         int
 }
 
 func (c config) String() string {
-	return fmt.Sprintf("update_interval=%s http_client_timeout=%s", c.updaterInterval.String(), c.httpClientTimeout.String())
+	return fmt.Sprintf("update_interval=%s update_concurrency=%d update_host_concurrency=%d failure_threshold=%d success_threshold=%d retries=%d retry_backoff=%s http_client_timeout=%s database=%s database_path=%s history_limit=%d history_retention=%s webhook=%t webhook_retries=%d",
+		c.updaterInterval.String(), c.updaterConcurrency, c.updaterHostConcurrency, c.failureThreshold, c.successThreshold,
+		c.retries, c.retryBackoff.String(), c.httpClientTimeout.String(), c.databaseDriver, c.databasePath, c.historyLimit, c.historyRetention.String(),
+		c.webhookURL != "", c.webhookRetries)
 }
 
 func parseFlag() (*config, error) {
-	updaterIntervalFlag := flag.String("interval", "5m", "Updater interval")
-	httpClientTimeoutFlag := flag.String("timeout", "800ms", "Updater interval")
+	updaterIntervalFlag := flag.String("interval", "5m", "Default interval between two checks of a website")
+	updaterConcurrencyFlag := flag.Int("concurrency", 10, "Maximum number of websites checked at the same time")
+	updaterHostConcurrencyFlag := flag.Int("host-concurrency", 2, "Maximum number of websites of the same host checked at the same time (0 means unlimited)")
+	failureThresholdFlag := flag.Int("failure-threshold", 1, "Default number of consecutive failed checks before a website goes down")
+	successThresholdFlag := flag.Int("success-threshold", 1, "Default number of consecutive successful checks before a website goes up again")
+	retriesFlag := flag.Int("retries", 1, "Maximum number of retries of a failed check before the failure is reported (0 means no retries)")
+	retryBackoffFlag := flag.String("retry-backoff", "1s", "Delay before the first retry of a failed check, doubled on every following retry")
+	httpClientTimeoutFlag := flag.String("timeout", "800ms", "Default timeout of a check of a website")
+	databaseDriverFlag := flag.String("database", "memory", "Database driver to store websites (memory or file)")
+	databasePathFlag := flag.String("database-path", "gohealthz.db", "Path of the database file when using file database driver")
+	historyLimitFlag := flag.Int("history-limit", storage.DefaultHistoryLimit, "Maximum number of check results kept for every website (0 means unlimited)")
+	historyRetentionFlag := flag.String("history-retention", storage.DefaultHistoryRetention.String(), "How long check results are kept, uptime windows longer than this are not fully covered (0 means forever)")
+	webhookURLFlag := flag.String("webhook-url", "", "URL of the webhook notified whenever a website changes its state (empty means no notifications)")
+	webhookRetriesFlag := flag.Int("webhook-retries", 3, "Maximum number of retries of a failed webhook delivery (0 means no retries)")
 	helpFlag := flag.Bool("help", false, "print this message")
 	flag.Parse()
 	if *helpFlag {
@@ -36,9 +65,55 @@ func parseFlag() (*config, error) {
 		return nil, err
 	}
 
+	historyRetention, err := time.ParseDuration(*historyRetentionFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	retryBackoff, err := time.ParseDuration(*retryBackoffFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	if updaterInterval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", updaterInterval)
+	}
+
+	if *retriesFlag < 0 {
+		return nil, fmt.Errorf("retries must not be negative, got %d", *retriesFlag)
+	}
+
+	if *webhookRetriesFlag < 0 {
+		return nil, fmt.Errorf("webhook retries must not be negative, got %d", *webhookRetriesFlag)
+	}
+
+	if *updaterConcurrencyFlag < 1 {
+		return nil, fmt.Errorf("concurrency must be at least 1, got %d", *updaterConcurrencyFlag)
+	}
+
+	if *failureThresholdFlag < 1 || *successThresholdFlag < 1 {
+		return nil, fmt.Errorf("failure and success thresholds must be at least 1, got %d and %d", *failureThresholdFlag, *successThresholdFlag)
+	}
+
+	if *databaseDriverFlag != "memory" && *databaseDriverFlag != "file" {
+		return nil, fmt.Errorf("unknown database driver: %s", *databaseDriverFlag)
+	}
+
 	c := config{
-		updaterInterval:   updaterInterval,
-		httpClientTimeout: httpClientTimeout,
+		updaterInterval:        updaterInterval,
+		updaterConcurrency:     *updaterConcurrencyFlag,
+		updaterHostConcurrency: *updaterHostConcurrencyFlag,
+		failureThreshold:       *failureThresholdFlag,
+		successThreshold:       *successThresholdFlag,
+		retries:                *retriesFlag,
+		retryBackoff:           retryBackoff,
+		httpClientTimeout:      httpClientTimeout,
+		databaseDriver:         *databaseDriverFlag,
+		databasePath:           *databasePathFlag,
+		historyLimit:           *historyLimitFlag,
+		historyRetention:       historyRetention,
+		webhookURL:             *webhookURLFlag,
+		webhookRetries:         *webhookRetriesFlag,
 	}
 	return &c, nil
 }
@@ -1,18 +1,29 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
+	"syscall"
+	"time"
 
 	"github.com/ajiyakin/gohealthz/internal/pkg/handler"
+	"github.com/ajiyakin/gohealthz/internal/pkg/notifier"
+	"github.com/ajiyakin/gohealthz/internal/pkg/state"
 	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
 	"github.com/ajiyakin/gohealthz/internal/pkg/updater"
 )
 
+// shutdownTimeout maximum duration to wait for in-flight requests and checks
+// on shutdown
+const shutdownTimeout = 10 * time.Second
+
 var (
 	apiJSONRaw []byte
 )
@@ -35,10 +46,37 @@ func main() {
 
 	fmt.Printf("starting service with configurations: %s\n", c.String())
 
-	http.DefaultClient.Timeout = c.httpClientTimeout
-	database := storage.NewInMemoryDatabase()
+	database, err := openDatabase(c)
+	if err != nil {
+		fmt.Printf("unable to open database: %v", err)
+		os.Exit(1)
+	}
 
-	updater.StartUpdate(database, c.updaterInterval)
+	var websiteNotifier notifier.Notifier
+	if c.webhookURL != "" {
+		websiteNotifier, err = notifier.NewWebhook(c.webhookURL, database, notifier.Config{Retries: c.webhookRetries})
+		if err != nil {
+			fmt.Printf("unable to create webhook notifier: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	checksCtx, cancelChecks := context.WithCancel(context.Background())
+	defer cancelChecks()
+	websiteUpdater := updater.New(database, updater.Config{
+		Interval:        c.updaterInterval,
+		Timeout:         c.httpClientTimeout,
+		Concurrency:     c.updaterConcurrency,
+		HostConcurrency: c.updaterHostConcurrency,
+		Thresholds: state.Thresholds{
+			Failure: c.failureThreshold,
+			Success: c.successThreshold,
+		},
+		Retries:      c.retries,
+		RetryBackoff: c.retryBackoff,
+		Notifier:     websiteNotifier,
+	})
+	websiteUpdater.Start(checksCtx)
 
 	ui := http.FileServer(http.Dir(path.Join("web", "static")))
 	http.Handle("/", ui)
@@ -56,7 +94,64 @@ func main() {
 		}
 	})
 
-	http.HandleFunc("/website", handler.NewWebsiteHandler(database))
+	http.HandleFunc("/website", handler.NewWebsiteHandler(database, c.httpClientTimeout))
+	http.HandleFunc("/website/{id}/history", handler.NewWebsiteHistoryHandler(database))
+	http.HandleFunc("/website/{id}/uptime", handler.NewWebsiteUptimeHandler(database))
+	http.HandleFunc("/website/{id}/check", handler.NewWebsiteCheckHandler(websiteUpdater))
+	http.HandleFunc("/ping/{token}", handler.NewPingHandler(database, websiteUpdater))
+	http.HandleFunc("/maintenance", handler.NewMaintenanceHandler(database))
+	http.HandleFunc("/webhook/deliveries", handler.NewDeliveryHandler(database))
+
+	server := &http.Server{Addr: ":8080"}
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	signalCtx, stopSignal := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stopSignal()
+	<-signalCtx.Done()
+	shutdown(server, websiteUpdater, database, cancelChecks)
+}
 
-	log.Fatal(http.ListenAndServe(":8080", nil))
+// shutdown stops accepting requests and waits for in-flight requests, checks
+// and their notifications to finish. Checks and notifications still running
+// after shutdownTimeout are cancelled, then the database is closed
+func shutdown(server *http.Server, websiteUpdater *updater.Updater, database storage.Database, cancelChecks context.CancelFunc) {
+	log.Printf("shutting down...")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("unable to shut down HTTP server gracefully: %v", err)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		websiteUpdater.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Printf("in-flight checks and notifications are not finished within %s, cancelling them", shutdownTimeout)
+		cancelChecks()
+		<-stopped
+	}
+	// nothing writes to the database anymore once the updater is stopped
+	if closer, ok := database.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Printf("unable to close database: %v", err)
+		}
+	}
+	log.Printf("...shut down")
+}
+
+func openDatabase(c *config) (storage.Database, error) {
+	if c.databaseDriver == "file" {
+		return storage.NewFileDatabase(c.databasePath, c.historyLimit, c.historyRetention)
+	}
+	database := storage.NewInMemoryDatabase()
+	database.SetHistoryLimit(c.historyLimit)
+	database.SetHistoryRetention(c.historyRetention)
+	return database, nil
 }
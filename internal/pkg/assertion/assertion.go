@@ -0,0 +1,213 @@
+package assertion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// DefaultMaxReadBodySize maximum number of bytes of the response body read
+// when evaluating assertions without maximum body size
+const DefaultMaxReadBodySize = 1 << 20
+
+// Error an assertion that is not met by a response
+type Error struct {
+	// Assertion the assertion that is not met (e.g. body_contains "ok")
+	Assertion string
+	// Reason why the assertion is not met
+	Reason string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("assertion %s failed: %s", err.Assertion, err.Reason)
+}
+
+// Validate checks whether assertions are well formed, e.g. every regular
+// expression can be compiled
+func Validate(assertions storage.Assertions) error {
+	for _, statusCodes := range assertions.StatusCodes {
+		if statusCodes.Min < 100 || statusCodes.Max > 599 || statusCodes.Min > statusCodes.Max {
+			return fmt.Errorf("invalid status code range %s", FormatStatusCodeRange(statusCodes))
+		}
+	}
+	for _, expressions := range [][]string{assertions.BodyMatches, assertions.BodyNotMatches} {
+		for _, expression := range expressions {
+			if _, err := regexp.Compile(expression); err != nil {
+				return fmt.Errorf("invalid regular expression %q: %v", expression, err)
+			}
+		}
+	}
+	for name := range assertions.Headers {
+		if name == "" {
+			return fmt.Errorf("header name must not be empty")
+		}
+	}
+	if assertions.MaxBodySize < 0 {
+		return fmt.Errorf("max body size must not be negative, got %d", assertions.MaxBodySize)
+	}
+	for _, expression := range assertions.JSON {
+		if _, err := parseJSONAssertion(expression); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadBody reads the response body so it can be evaluated. The body is read
+// until one byte past maximum body size of assertions, so an oversized body
+// can be detected without reading it completely. Nil body results in empty
+// body
+func ReadBody(response *http.Response, assertions storage.Assertions) ([]byte, error) {
+	if response.Body == nil {
+		return nil, nil
+	}
+	limit := int64(DefaultMaxReadBodySize)
+	if assertions.MaxBodySize > 0 {
+		limit = assertions.MaxBodySize + 1
+	}
+	return ioutil.ReadAll(io.LimitReader(response.Body, limit))
+}
+
+// Evaluate checks response (and its body read with ReadBody) against every
+// assertion, and returns *Error of the first assertion that is not met
+func Evaluate(assertions storage.Assertions, response *http.Response, body []byte) error {
+	if err := evaluateStatusCode(assertions.StatusCodes, response.StatusCode); err != nil {
+		return err
+	}
+	for name, expected := range assertions.Headers {
+		values, ok := response.Header[http.CanonicalHeaderKey(name)]
+		if !ok {
+			return &Error{Assertion: fmt.Sprintf("header %q", name), Reason: "header is missing"}
+		}
+		if expected != "" && !containsString(values, expected) {
+			return &Error{
+				Assertion: fmt.Sprintf("header %q == %q", name, expected),
+				Reason:    fmt.Sprintf("got %q", strings.Join(values, ", ")),
+			}
+		}
+	}
+	if assertions.MaxBodySize > 0 && int64(len(body)) > assertions.MaxBodySize {
+		return &Error{
+			Assertion: fmt.Sprintf("max_body_size %d", assertions.MaxBodySize),
+			Reason:    "body is larger than maximum size",
+		}
+	}
+	for _, substring := range assertions.BodyContains {
+		if !bytes.Contains(body, []byte(substring)) {
+			return &Error{Assertion: fmt.Sprintf("body_contains %q", substring), Reason: "substring not found"}
+		}
+	}
+	for _, substring := range assertions.BodyNotContains {
+		if bytes.Contains(body, []byte(substring)) {
+			return &Error{Assertion: fmt.Sprintf("body_not_contains %q", substring), Reason: "substring found"}
+		}
+	}
+	for _, expression := range assertions.BodyMatches {
+		matched, err := regexp.Match(expression, body)
+		if err != nil {
+			return &Error{Assertion: fmt.Sprintf("body_matches %q", expression), Reason: err.Error()}
+		}
+		if !matched {
+			return &Error{Assertion: fmt.Sprintf("body_matches %q", expression), Reason: "body does not match"}
+		}
+	}
+	for _, expression := range assertions.BodyNotMatches {
+		matched, err := regexp.Match(expression, body)
+		if err != nil {
+			return &Error{Assertion: fmt.Sprintf("body_not_matches %q", expression), Reason: err.Error()}
+		}
+		if matched {
+			return &Error{Assertion: fmt.Sprintf("body_not_matches %q", expression), Reason: "body matches"}
+		}
+	}
+	return evaluateJSON(assertions.JSON, body)
+}
+
+func evaluateJSON(expressions []string, body []byte) error {
+	if len(expressions) == 0 {
+		return nil
+	}
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return &Error{Assertion: fmt.Sprintf("json %s", expressions[0]), Reason: "body is not valid JSON"}
+	}
+	for _, expression := range expressions {
+		jsonAssertion, err := parseJSONAssertion(expression)
+		if err != nil {
+			return &Error{Assertion: fmt.Sprintf("json %s", expression), Reason: err.Error()}
+		}
+		if err = jsonAssertion.evaluate(document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FailedAssertion returns the assertion that is not met when err is *Error,
+// otherwise empty string
+func FailedAssertion(err error) string {
+	if assertionErr, ok := err.(*Error); ok {
+		return assertionErr.Assertion
+	}
+	return ""
+}
+
+func evaluateStatusCode(statusCodes []storage.StatusCodeRange, statusCode int) error {
+	if len(statusCodes) == 0 {
+		statusCodes = []storage.StatusCodeRange{{Min: http.StatusOK, Max: http.StatusOK}}
+	}
+	allowed := make([]string, 0, len(statusCodes))
+	for _, statusCodeRange := range statusCodes {
+		if statusCode >= statusCodeRange.Min && statusCode <= statusCodeRange.Max {
+			return nil
+		}
+		allowed = append(allowed, FormatStatusCodeRange(statusCodeRange))
+	}
+	return &Error{
+		Assertion: fmt.Sprintf("status_codes [%s]", strings.Join(allowed, ", ")),
+		Reason:    fmt.Sprintf("got status code %d", statusCode),
+	}
+}
+
+// ParseStatusCodeRange parses status code range in form of a single status
+// code (e.g. "204") or range of status codes (e.g. "200-299")
+func ParseStatusCodeRange(value string) (storage.StatusCodeRange, error) {
+	parts := strings.SplitN(value, "-", 2)
+	min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return storage.StatusCodeRange{}, fmt.Errorf("invalid status code range %q", value)
+	}
+	max := min
+	if len(parts) == 2 {
+		if max, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return storage.StatusCodeRange{}, fmt.Errorf("invalid status code range %q", value)
+		}
+	}
+	return storage.StatusCodeRange{Min: min, Max: max}, nil
+}
+
+// FormatStatusCodeRange formats status code range in the same form parsed by
+// ParseStatusCodeRange
+func FormatStatusCodeRange(statusCodeRange storage.StatusCodeRange) string {
+	if statusCodeRange.Min == statusCodeRange.Max {
+		return strconv.Itoa(statusCodeRange.Min)
+	}
+	return fmt.Sprintf("%d-%d", statusCodeRange.Min, statusCodeRange.Max)
+}
+
+func containsString(values []string, expected string) bool {
+	for _, value := range values {
+		if value == expected {
+			return true
+		}
+	}
+	return false
+}
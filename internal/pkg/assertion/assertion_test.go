@@ -0,0 +1,196 @@
+package assertion
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+func newResponse(statusCode int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: statusCode, Header: header}
+}
+
+func TestEvaluateSuccess(t *testing.T) {
+	// arrange
+	assertions := storage.Assertions{
+		StatusCodes:     []storage.StatusCodeRange{{Min: 200, Max: 299}, {Min: 301, Max: 301}},
+		BodyContains:    []string{"ok"},
+		BodyNotContains: []string{"error"},
+		BodyMatches:     []string{`version": "\d+\.\d+"`},
+		BodyNotMatches:  []string{`(?i)maintenance`},
+		Headers:         map[string]string{"content-type": "application/json", "X-Request-Id": ""},
+		MaxBodySize:     64,
+	}
+	response := newResponse(http.StatusNoContent, http.Header{
+		"Content-Type": []string{"application/json"},
+		"X-Request-Id": []string{"1234"},
+	})
+
+	// action
+	err := Evaluate(assertions, response, []byte(`{"status": "ok", "version": "1.2"}`))
+
+	// acceptance
+	if err != nil {
+		t.Errorf("expected every assertion to be met, got %v", err)
+	}
+}
+
+func TestEvaluateDefaultStatusCode(t *testing.T) {
+	// arrange
+	statusCodeTests := []struct {
+		testName   string
+		statusCode int
+		healthy    bool
+	}{
+		{"status code 200", http.StatusOK, true},
+		{"status code 204", http.StatusNoContent, false},
+		{"status code 500", http.StatusInternalServerError, false},
+	}
+
+	for _, tt := range statusCodeTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			err := Evaluate(storage.Assertions{}, newResponse(tt.statusCode, nil), nil)
+
+			// acceptance
+			if (err == nil) != tt.healthy {
+				t.Errorf("expected healthy %v, got error %v", tt.healthy, err)
+			}
+		})
+	}
+}
+
+func TestEvaluateFailure(t *testing.T) {
+	// arrange
+	failureTests := []struct {
+		testName   string
+		assertions storage.Assertions
+		header     http.Header
+		body       string
+		assertion  string
+	}{
+		{
+			"status code out of range",
+			storage.Assertions{StatusCodes: []storage.StatusCodeRange{{Min: 300, Max: 399}}},
+			nil,
+			"",
+			"status_codes [300-399]",
+		}, {
+			"missing substring",
+			storage.Assertions{BodyContains: []string{"healthy"}},
+			nil,
+			"status: down",
+			`body_contains "healthy"`,
+		}, {
+			"forbidden substring",
+			storage.Assertions{BodyNotContains: []string{"error"}},
+			nil,
+			"internal error",
+			`body_not_contains "error"`,
+		}, {
+			"body does not match",
+			storage.Assertions{BodyMatches: []string{`^ok$`}},
+			nil,
+			"not ok",
+			`body_matches "^ok$"`,
+		}, {
+			"body matches forbidden expression",
+			storage.Assertions{BodyNotMatches: []string{`down|degraded`}},
+			nil,
+			"status: degraded",
+			`body_not_matches "down|degraded"`,
+		}, {
+			"missing header",
+			storage.Assertions{Headers: map[string]string{"X-Version": ""}},
+			nil,
+			"",
+			`header "X-Version"`,
+		}, {
+			"header with different value",
+			storage.Assertions{Headers: map[string]string{"Content-Type": "application/json"}},
+			http.Header{"Content-Type": []string{"text/html"}},
+			"",
+			`header "Content-Type" == "application/json"`,
+		}, {
+			"body too large",
+			storage.Assertions{MaxBodySize: 4},
+			nil,
+			"too large",
+			"max_body_size 4",
+		},
+	}
+
+	for _, tt := range failureTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			err := Evaluate(tt.assertions, newResponse(http.StatusOK, tt.header), []byte(tt.body))
+
+			// acceptance
+			assertionErr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("expected assertion error, got %v", err)
+			}
+			if tt.assertion != "" && assertionErr.Assertion != tt.assertion {
+				t.Errorf("expected failing assertion %s, got %s", tt.assertion, assertionErr.Assertion)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidAssertions(t *testing.T) {
+	// arrange
+	invalidTests := []struct {
+		testName   string
+		assertions storage.Assertions
+	}{
+		{"status code range reversed", storage.Assertions{StatusCodes: []storage.StatusCodeRange{{Min: 299, Max: 200}}}},
+		{"status code out of bound", storage.Assertions{StatusCodes: []storage.StatusCodeRange{{Min: 200, Max: 700}}}},
+		{"invalid regular expression", storage.Assertions{BodyMatches: []string{"("}}},
+		{"negative max body size", storage.Assertions{MaxBodySize: -1}},
+	}
+
+	for _, tt := range invalidTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			err := Validate(tt.assertions)
+
+			// acceptance
+			if err == nil {
+				t.Errorf("expected assertions to be invalid")
+			}
+		})
+	}
+}
+
+func TestParseStatusCodeRange(t *testing.T) {
+	// arrange
+	rangeTests := []struct {
+		value    string
+		expected storage.StatusCodeRange
+	}{
+		{"204", storage.StatusCodeRange{Min: 204, Max: 204}},
+		{"200-299", storage.StatusCodeRange{Min: 200, Max: 299}},
+	}
+
+	for _, tt := range rangeTests {
+		t.Run(tt.value, func(t *testing.T) {
+			// action
+			actual, err := ParseStatusCodeRange(tt.value)
+
+			// acceptance
+			if err != nil {
+				t.Errorf("unable to parse status code range: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected %#v got %#v", tt.expected, actual)
+			}
+			if formatted := FormatStatusCodeRange(actual); formatted != tt.value {
+				t.Errorf("expected formatted range %s, got %s", tt.value, formatted)
+			}
+		})
+	}
+}
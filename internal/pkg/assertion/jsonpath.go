@@ -0,0 +1,246 @@
+package assertion
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonOperators supported comparison operators of JSON assertion. Two
+// characters operators must come first so "<=" is not parsed as "<"
+var jsonOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// jsonAssertion a JSONPath-style assertion on a JSON body, e.g.
+// `$.status == "ok"` or `$.checks[*].healthy == true`. Assertion without
+// operator (e.g. `$.version`) only requires the path to exist. When the path
+// contains wildcard, every matched value must meet the assertion
+type jsonAssertion struct {
+	expression string
+	path       []pathSegment
+	operator   string
+	expected   interface{}
+}
+
+// pathSegment a single step of JSON path, either an object key, an array
+// index, or a wildcard matching every element of an array (or object)
+type pathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// jsonValue a value within JSON document along with its concrete path
+type jsonValue struct {
+	path  string
+	value interface{}
+}
+
+// parseJSONAssertion parses JSON assertion expression
+func parseJSONAssertion(expression string) (jsonAssertion, error) {
+	assertion := jsonAssertion{expression: expression}
+	rest := strings.TrimSpace(expression)
+	if !strings.HasPrefix(rest, "$") {
+		return assertion, fmt.Errorf("invalid JSON assertion %q: path must start with $", expression)
+	}
+	rest = rest[1:]
+	for len(rest) > 0 && (rest[0] == '.' || rest[0] == '[') {
+		var segment pathSegment
+		var err error
+		segment, rest, err = parsePathSegment(rest)
+		if err != nil {
+			return assertion, fmt.Errorf("invalid JSON assertion %q: %v", expression, err)
+		}
+		assertion.path = append(assertion.path, segment)
+	}
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return assertion, nil
+	}
+	for _, operator := range jsonOperators {
+		if strings.HasPrefix(rest, operator) {
+			assertion.operator = operator
+			break
+		}
+	}
+	if assertion.operator == "" {
+		return assertion, fmt.Errorf("invalid JSON assertion %q: unknown operator at %q", expression, rest)
+	}
+	literal := strings.TrimSpace(rest[len(assertion.operator):])
+	if err := json.Unmarshal([]byte(literal), &assertion.expected); err != nil {
+		return assertion, fmt.Errorf("invalid JSON assertion %q: value %s is not a JSON literal", expression, literal)
+	}
+	if assertion.operator != "==" && assertion.operator != "!=" {
+		if _, ok := assertion.expected.(float64); !ok {
+			return assertion, fmt.Errorf("invalid JSON assertion %q: operator %s requires a number", expression, assertion.operator)
+		}
+	}
+	return assertion, nil
+}
+
+// parsePathSegment parses one segment at the beginning of path, and returns
+// the rest of the path
+func parsePathSegment(path string) (pathSegment, string, error) {
+	if path[0] == '.' {
+		end := 1
+		for end < len(path) && isKeyCharacter(path[end]) {
+			end++
+		}
+		if end == 1 {
+			if end < len(path) && path[end] == '*' {
+				return pathSegment{wildcard: true}, path[2:], nil
+			}
+			return pathSegment{}, "", fmt.Errorf("missing key after .")
+		}
+		return pathSegment{key: path[1:end]}, path[end:], nil
+	}
+	end := strings.IndexByte(path, ']')
+	if end < 0 {
+		return pathSegment{}, "", fmt.Errorf("missing ]")
+	}
+	inside := strings.TrimSpace(path[1:end])
+	rest := path[end+1:]
+	if inside == "*" {
+		return pathSegment{wildcard: true}, rest, nil
+	}
+	if strings.HasPrefix(inside, `"`) || strings.HasPrefix(inside, `'`) {
+		if len(inside) < 2 || inside[len(inside)-1] != inside[0] {
+			return pathSegment{}, "", fmt.Errorf("unterminated key %s", inside)
+		}
+		return pathSegment{key: inside[1 : len(inside)-1]}, rest, nil
+	}
+	index, err := strconv.Atoi(inside)
+	if err != nil || index < 0 {
+		return pathSegment{}, "", fmt.Errorf("invalid array index %s", inside)
+	}
+	return pathSegment{index: index, isIndex: true}, rest, nil
+}
+
+func isKeyCharacter(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// evaluate evaluates the assertion against decoded JSON document. Below a
+// wildcard, every matched element must contain the rest of the path
+func (assertion jsonAssertion) evaluate(document interface{}) error {
+	values := []jsonValue{{path: "$", value: document}}
+	wildcard := false
+	for _, segment := range assertion.path {
+		var next []jsonValue
+		for _, current := range values {
+			matched := segment.apply(current)
+			if len(matched) == 0 && wildcard && !segment.wildcard {
+				return &Error{
+					Assertion: fmt.Sprintf("json %s", assertion.expression),
+					Reason:    fmt.Sprintf("%s%s not found", current.path, segment),
+				}
+			}
+			next = append(next, matched...)
+		}
+		values = next
+		wildcard = wildcard || segment.wildcard
+	}
+	if len(values) == 0 {
+		return &Error{Assertion: fmt.Sprintf("json %s", assertion.expression), Reason: "path not found"}
+	}
+	if assertion.operator == "" {
+		return nil
+	}
+	for _, actual := range values {
+		if !assertion.compare(actual.value) {
+			return &Error{
+				Assertion: fmt.Sprintf("json %s", assertion.expression),
+				Reason:    fmt.Sprintf("%s is %s", actual.path, formatJSON(actual.value)),
+			}
+		}
+	}
+	return nil
+}
+
+func (assertion jsonAssertion) compare(actual interface{}) bool {
+	switch assertion.operator {
+	case "==":
+		return reflect.DeepEqual(actual, assertion.expected)
+	case "!=":
+		return !reflect.DeepEqual(actual, assertion.expected)
+	}
+	number, ok := actual.(float64)
+	if !ok {
+		return false
+	}
+	expected := assertion.expected.(float64)
+	switch assertion.operator {
+	case "<":
+		return number < expected
+	case "<=":
+		return number <= expected
+	case ">":
+		return number > expected
+	case ">=":
+		return number >= expected
+	}
+	return false
+}
+
+// String returns the segment the way it is written within a path
+func (segment pathSegment) String() string {
+	switch {
+	case segment.wildcard:
+		return "[*]"
+	case segment.isIndex:
+		return fmt.Sprintf("[%d]", segment.index)
+	}
+	return "." + segment.key
+}
+
+// apply returns values matched by the segment within current value
+func (segment pathSegment) apply(current jsonValue) []jsonValue {
+	switch value := current.value.(type) {
+	case map[string]interface{}:
+		if segment.wildcard {
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			// sorted so the first failing value is always the same one
+			sort.Strings(keys)
+			values := make([]jsonValue, 0, len(keys))
+			for _, key := range keys {
+				values = append(values, jsonValue{path: current.path + "." + key, value: value[key]})
+			}
+			return values
+		}
+		if segment.isIndex {
+			return nil
+		}
+		child, ok := value[segment.key]
+		if !ok {
+			return nil
+		}
+		return []jsonValue{{path: current.path + "." + segment.key, value: child}}
+	case []interface{}:
+		if segment.wildcard {
+			values := make([]jsonValue, 0, len(value))
+			for index, child := range value {
+				values = append(values, jsonValue{path: fmt.Sprintf("%s[%d]", current.path, index), value: child})
+			}
+			return values
+		}
+		if !segment.isIndex || segment.index >= len(value) {
+			return nil
+		}
+		return []jsonValue{{path: fmt.Sprintf("%s[%d]", current.path, segment.index), value: value[segment.index]}}
+	}
+	return nil
+}
+
+func formatJSON(value interface{}) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(raw)
+}
@@ -0,0 +1,108 @@
+package assertion
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+const healthBody = `{
+	"status": "ok",
+	"version": "1.2.0",
+	"uptime": 3600,
+	"checks": [
+		{"name": "database", "healthy": true, "latency": 12},
+		{"name": "cache", "healthy": true, "latency": 3}
+	],
+	"dependencies": {"payment": {"status": "ok"}, "mail": {"status": "ok"}}
+}`
+
+func TestEvaluateJSONSuccess(t *testing.T) {
+	// arrange
+	expressionTests := []string{
+		`$.status == "ok"`,
+		`$["status"] != "degraded"`,
+		`$.version`,
+		`$.uptime >= 60`,
+		`$.checks[0].name == "database"`,
+		`$.checks[*].healthy == true`,
+		`$.checks[*].latency < 100`,
+		`$.dependencies.*.status == "ok"`,
+		`$.dependencies['payment'] == {"status": "ok"}`,
+	}
+
+	for _, expression := range expressionTests {
+		t.Run(expression, func(t *testing.T) {
+			// action
+			err := Evaluate(storage.Assertions{JSON: []string{expression}}, newResponse(http.StatusOK, nil), []byte(healthBody))
+
+			// acceptance
+			if err != nil {
+				t.Errorf("expected assertion to be met, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEvaluateJSONFailure(t *testing.T) {
+	// arrange
+	failureTests := []struct {
+		expression string
+		body       string
+		reason     string
+	}{
+		{`$.status == "ok"`, `{"status": "degraded"}`, `$.status is "degraded"`},
+		{`$.checks[*].healthy == true`, `{"checks": [{"healthy": true}, {"healthy": false}]}`, `$.checks[1].healthy is false`},
+		{`$.checks[*].latency < 100`, `{"checks": [{"latency": 250}]}`, `$.checks[0].latency is 250`},
+		{`$.checks[*].healthy == true`, `{"checks": [{"healthy": true}, {"name": "cache"}]}`, `$.checks[1].healthy not found`},
+		{`$.checks[*].healthy`, `{"checks": [{"name": "database"}]}`, `$.checks[0].healthy not found`},
+		{`$.dependencies.*.status == "ok"`, `{"dependencies": {"mail": {}, "payment": {"status": "ok"}}}`, `$.dependencies.mail.status not found`},
+		{`$.version`, `{"status": "ok"}`, `path not found`},
+		{`$.checks[3].healthy == true`, `{"checks": []}`, `path not found`},
+		{`$.status == "ok"`, `<html>ok</html>`, `body is not valid JSON`},
+	}
+
+	for _, tt := range failureTests {
+		t.Run(tt.expression, func(t *testing.T) {
+			// action
+			err := Evaluate(storage.Assertions{JSON: []string{tt.expression}}, newResponse(http.StatusOK, nil), []byte(tt.body))
+
+			// acceptance
+			assertionErr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("expected assertion error, got %v", err)
+			}
+			if assertionErr.Assertion != "json "+tt.expression {
+				t.Errorf("expected failing assertion json %s, got %s", tt.expression, assertionErr.Assertion)
+			}
+			if assertionErr.Reason != tt.reason {
+				t.Errorf("expected reason %s, got %s", tt.reason, assertionErr.Reason)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidJSONAssertions(t *testing.T) {
+	// arrange
+	invalidTests := []string{
+		`status == "ok"`,
+		`$.status = "ok"`,
+		`$.status == ok`,
+		`$.checks[x].healthy == true`,
+		`$.checks[0.healthy == true`,
+		`$.status > "ok"`,
+	}
+
+	for _, expression := range invalidTests {
+		t.Run(expression, func(t *testing.T) {
+			// action
+			err := Validate(storage.Assertions{JSON: []string{expression}})
+
+			// acceptance
+			if err == nil {
+				t.Errorf("expected JSON assertion to be invalid")
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package backoff
+
+import (
+	"context"
+	"time"
+)
+
+// Max upper bound of delay between two attempts
+const Max = 30 * time.Second
+
+// Delay returns delay after the failed attempt, base doubled for every
+// previous retry and capped at Max
+func Delay(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < Max; i++ {
+		delay *= 2
+	}
+	if delay > Max {
+		delay = Max
+	}
+	return delay
+}
+
+// Jitter spreads delay over its upper half by jitter (random fraction in
+// [0, 1)), so retries of attempts that fail together do not happen at once
+func Jitter(delay time.Duration, jitter float64) time.Duration {
+	return delay/2 + time.Duration(jitter*float64(delay/2))
+}
+
+// Sleep waits for duration d, or returns error of ctx once it is done
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,61 @@
+package backoff
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	delayTests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{10, Max},
+	}
+
+	for _, tt := range delayTests {
+		t.Run(fmt.Sprint(tt.attempt), func(t *testing.T) {
+			if actual := Delay(time.Second, tt.attempt); actual != tt.expected {
+				t.Errorf("expected delay %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestJitter(t *testing.T) {
+	jitterTests := []struct {
+		jitter   float64
+		expected time.Duration
+	}{
+		{0, 500 * time.Millisecond},
+		{0.5, 750 * time.Millisecond},
+		{0.99, 995 * time.Millisecond},
+	}
+
+	for _, tt := range jitterTests {
+		t.Run(fmt.Sprint(tt.jitter), func(t *testing.T) {
+			if actual := Jitter(time.Second, tt.jitter); actual != tt.expected {
+				t.Errorf("expected delay %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSleepStopsOnceCancelled(t *testing.T) {
+	// arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// action
+	err := Sleep(ctx, time.Hour)
+
+	// acceptance
+	if err != context.Canceled {
+		t.Errorf("expected error %v, got %v", context.Canceled, err)
+	}
+}
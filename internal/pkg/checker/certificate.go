@@ -0,0 +1,74 @@
+package checker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// DefaultCertificateExpiryDays number of days before its certificate expires
+// a website is considered degraded, used when the website has no number of
+// days of its own
+const DefaultCertificateExpiryDays = 14
+
+// day duration of a day used to count days to expiry of a certificate
+const day = 24 * time.Hour
+
+// newCertificate returns the leaf certificate of the connection, nil when no
+// certificate is presented
+func newCertificate(state tls.ConnectionState, chainValid bool) *storage.Certificate {
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := state.PeerCertificates[0]
+	return &storage.Certificate{
+		NotAfter:   leaf.NotAfter,
+		Issuer:     leaf.Issuer.String(),
+		DNSNames:   leaf.DNSNames,
+		ChainValid: chainValid,
+	}
+}
+
+// verifyCertificate verifies certificate chain presented on the connection
+// against roots (system roots when nil) and serverName
+func verifyCertificate(state tls.ConnectionState, serverName string, roots *x509.CertPool, now time.Time) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("no certificate presented")
+	}
+	intermediates := x509.NewCertPool()
+	for _, certificate := range state.PeerCertificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	return err
+}
+
+// DaysToExpiry returns number of whole days until the certificate expires,
+// negative when it is already expired
+func DaysToExpiry(certificate storage.Certificate, now time.Time) int {
+	return int(certificate.NotAfter.Sub(now) / day)
+}
+
+// checkCertificateExpiry marks an up website as degraded when its
+// certificate expires within the number of days of the website
+func checkCertificateExpiry(result *storage.CheckResult, website storage.Website, now time.Time) {
+	if result.Certificate == nil || result.State != storage.StateUp {
+		return
+	}
+	expiryDays := website.CertificateExpiryDays
+	if expiryDays <= 0 {
+		expiryDays = DefaultCertificateExpiryDays
+	}
+	if days := DaysToExpiry(*result.Certificate, now); days < expiryDays {
+		result.State = storage.StateDegraded
+		result.Error = fmt.Sprintf("certificate expires in %d days", days)
+	}
+}
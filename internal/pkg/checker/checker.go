@@ -0,0 +1,105 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// Checker probes a website with a single type of check (e.g. HTTP)
+type Checker interface {
+	// Check probes the website once. State of the result is down when the
+	// probe fails, otherwise up, or degraded when the probe itself detects
+	// degradation. Unknown state means there is nothing to report yet and
+	// the result must not be recorded. Check must give up once ctx is done
+	Check(ctx context.Context, website storage.Website) storage.CheckResult
+	// Validate checks whether the website can be checked, e.g. its URL is
+	// well formed
+	Validate(website storage.Website) error
+}
+
+// Registry checkers by type of check they support
+type Registry map[storage.CheckType]Checker
+
+// NewRegistry initialize registry of every supported type of check, HTTP
+// checks and WebSocket handshakes are sent with client
+func NewRegistry(client *http.Client) Registry {
+	return Registry{
+		storage.CheckHTTP:      &HTTP{Client: client},
+		storage.CheckTCP:       &TCP{},
+		storage.CheckTLS:       &TLS{},
+		storage.CheckDNS:       &DNS{},
+		storage.CheckGRPC:      &GRPC{},
+		storage.CheckWebSocket: &WebSocket{Client: client},
+		storage.CheckHeartbeat: &Heartbeat{},
+	}
+}
+
+// WithNow returns a copy of registry whose heartbeat checker takes the current
+// time from now, so missed pings are detected by the same clock that
+// schedules the checks
+func (registry Registry) WithNow(now func() time.Time) Registry {
+	copied := make(Registry, len(registry))
+	for checkType, checker := range registry {
+		copied[checkType] = checker
+	}
+	if _, ok := copied[storage.CheckHeartbeat].(*Heartbeat); ok {
+		copied[storage.CheckHeartbeat] = &Heartbeat{Now: now}
+	}
+	return copied
+}
+
+// Supports returns whether a checker of checkType is registered, empty type
+// means HTTP
+func (registry Registry) Supports(checkType storage.CheckType) bool {
+	_, ok := registry[TypeOf(storage.Website{CheckType: checkType})]
+	return ok
+}
+
+// Validate checks whether website can be checked by the checker of its type
+func (registry Registry) Validate(website storage.Website) error {
+	checker, ok := registry[TypeOf(website)]
+	if !ok {
+		return fmt.Errorf("unsupported check type %q", TypeOf(website))
+	}
+	return checker.Validate(website)
+}
+
+// Check probes website with the checker of its type. ID, time and
+// healthiness of the result are filled in, and a website responding slower
+// than its degraded latency is considered degraded
+func (registry Registry) Check(ctx context.Context, website storage.Website) storage.CheckResult {
+	started := time.Now()
+	var result storage.CheckResult
+	checker, ok := registry[TypeOf(website)]
+	if ok {
+		result = checker.Check(ctx, website)
+	} else {
+		result.Error = fmt.Sprintf("unsupported check type %q", TypeOf(website))
+	}
+	result.WebsiteID = website.ID
+	result.Time = started
+	result.Attempts = 1
+	if result.Latency == 0 {
+		result.Latency = time.Since(started)
+	}
+	if result.State == "" {
+		result.State = storage.StateDown
+	}
+	if result.State == storage.StateUp && website.DegradedLatency > 0 && result.Latency > website.DegradedLatency {
+		result.State = storage.StateDegraded
+	}
+	result.Healthy = result.State == storage.StateUp || result.State == storage.StateDegraded
+	return result
+}
+
+// TypeOf returns type of check of website, HTTP when it is not set
+func TypeOf(website storage.Website) storage.CheckType {
+	if website.CheckType == "" {
+		return storage.CheckHTTP
+	}
+	return website.CheckType
+}
@@ -0,0 +1,109 @@
+package checker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// checkerFunc checker implemented by a function
+type checkerFunc func(ctx context.Context, website storage.Website) storage.CheckResult
+
+func (f checkerFunc) Check(ctx context.Context, website storage.Website) storage.CheckResult {
+	return f(ctx, website)
+}
+
+func (f checkerFunc) Validate(website storage.Website) error {
+	return nil
+}
+
+func TestRegistryCheckDispatchesByType(t *testing.T) {
+	// arrange
+	var checkedType storage.CheckType
+	registry := Registry{
+		storage.CheckHTTP: checkerFunc(func(ctx context.Context, website storage.Website) storage.CheckResult {
+			checkedType = storage.CheckHTTP
+			return storage.CheckResult{State: storage.StateUp}
+		}),
+		"fake": checkerFunc(func(ctx context.Context, website storage.Website) storage.CheckResult {
+			checkedType = "fake"
+			return storage.CheckResult{State: storage.StateUp}
+		}),
+	}
+	dispatchTests := []struct {
+		checkType storage.CheckType
+		expected  storage.CheckType
+	}{
+		{"", storage.CheckHTTP},
+		{storage.CheckHTTP, storage.CheckHTTP},
+		{"fake", "fake"},
+	}
+
+	for _, tt := range dispatchTests {
+		t.Run(string(tt.expected), func(t *testing.T) {
+			// action
+			result := registry.Check(context.Background(), storage.Website{ID: "123", CheckType: tt.checkType})
+
+			// acceptance
+			if checkedType != tt.expected {
+				t.Errorf("expected %s checker to be used, got %s", tt.expected, checkedType)
+			}
+			if result.WebsiteID != "123" || !result.Healthy || result.Time.IsZero() {
+				t.Errorf("expected healthy result of website 123 with time, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestRegistryCheckUnsupportedType(t *testing.T) {
+	// arrange
+	registry := Registry{}
+
+	// action
+	result := registry.Check(context.Background(), storage.Website{ID: "123", CheckType: "fake"})
+
+	// acceptance
+	if result.State != storage.StateDown || result.Healthy {
+		t.Errorf("expected website with unsupported check type to be down, got %s", result.State)
+	}
+	if result.Error != `unsupported check type "fake"` {
+		t.Errorf("expected unsupported check type error, got %q", result.Error)
+	}
+	if registry.Supports("fake") {
+		t.Errorf("expected fake check type to be unsupported")
+	}
+}
+
+func TestRegistryCheckDegradedLatency(t *testing.T) {
+	// arrange
+	registry := Registry{
+		storage.CheckHTTP: checkerFunc(func(ctx context.Context, website storage.Website) storage.CheckResult {
+			return storage.CheckResult{State: storage.StateUp, Latency: 200 * time.Millisecond}
+		}),
+	}
+	latencyTests := []struct {
+		degradedLatency time.Duration
+		expected        storage.State
+	}{
+		{0, storage.StateUp},
+		{time.Second, storage.StateUp},
+		{100 * time.Millisecond, storage.StateDegraded},
+	}
+
+	for _, tt := range latencyTests {
+		t.Run(tt.degradedLatency.String(), func(t *testing.T) {
+			// action
+			result := registry.Check(context.Background(), storage.Website{ID: "123", DegradedLatency: tt.degradedLatency})
+
+			// acceptance
+			if result.State != tt.expected {
+				t.Errorf("expected state %s, got %s", tt.expected, result.State)
+			}
+			if !result.Healthy {
+				t.Errorf("expected website to be healthy")
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/assertion"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// supportedRecordTypes types of DNS records supported by DNS check
+var supportedRecordTypes = []string{"A", "AAAA", "CNAME", "TXT", "MX"}
+
+// DNS checks a website by resolving records of its domain name (stored as URL
+// of the website). The website is up when every expected value is resolved
+type DNS struct{}
+
+// Validate implements Checker, URL of the website must be a domain name
+func (checker *DNS) Validate(website storage.Website) error {
+	name := strings.TrimSuffix(website.URL, ".")
+	if name == "" || strings.ContainsAny(name, "/:@ ") {
+		return fmt.Errorf("domain name must not be empty nor contain scheme, port or path, got %q", website.URL)
+	}
+	recordType := recordTypeOf(website.DNS)
+	if !containsString(supportedRecordTypes, recordType) {
+		return fmt.Errorf("unsupported record type %q, must be one of %s", website.DNS.RecordType, strings.Join(supportedRecordTypes, ", "))
+	}
+	if website.DNS.Resolver != "" {
+		if _, _, err := net.SplitHostPort(website.DNS.Resolver); err != nil {
+			return fmt.Errorf("resolver must be in form of host:port: %v", err)
+		}
+	}
+	for _, expected := range website.DNS.Expected {
+		if recordType != "A" && recordType != "AAAA" {
+			continue
+		}
+		ip := net.ParseIP(expected)
+		if ip == nil || (recordType == "A") != (ip.To4() != nil) {
+			return fmt.Errorf("expected value %q is not an address of %s record", expected, recordType)
+		}
+	}
+	return nil
+}
+
+// Check implements Checker
+func (checker *DNS) Check(ctx context.Context, website storage.Website) storage.CheckResult {
+	result := storage.CheckResult{State: storage.StateDown}
+	recordType := recordTypeOf(website.DNS)
+	started := time.Now()
+	records, err := resolve(ctx, newResolver(website.DNS.Resolver), recordType, website.URL)
+	result.Latency = time.Since(started)
+	result.Timings.DNS = result.Latency
+	if err == nil {
+		err = evaluateRecords(recordType, website.DNS.Expected, records)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.FailedAssertion = assertion.FailedAssertion(err)
+		return result
+	}
+	result.State = storage.StateUp
+	return result
+}
+
+// newResolver returns resolver querying DNS server at address, or the
+// resolver of the system when address is empty
+func newResolver(address string) *net.Resolver {
+	if address == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// resolve resolves records of recordType of name, every record is normalized
+// so it can be compared against expected value
+func resolve(ctx context.Context, resolver *net.Resolver, recordType, name string) ([]string, error) {
+	// fully qualified name so search domains of the system are not tried
+	name = strings.TrimSuffix(name, ".") + "."
+	var records []string
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			records = append(records, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, normalizeName(cname))
+	case "TXT":
+		txts, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, txts...)
+	case "MX":
+		mxs, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			records = append(records, normalizeName(mx.Host))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported record type %q", recordType)
+	}
+	return records, nil
+}
+
+// evaluateRecords checks whether every expected value is within records
+func evaluateRecords(recordType string, expected []string, records []string) error {
+	if len(records) == 0 {
+		return &assertion.Error{Assertion: fmt.Sprintf("dns %s", recordType), Reason: "no record found"}
+	}
+	for _, value := range expected {
+		if !containsString(records, normalizeRecord(recordType, value)) {
+			return &assertion.Error{
+				Assertion: fmt.Sprintf("dns %s contains %q", recordType, value),
+				Reason:    fmt.Sprintf("got %s", formatRecords(records)),
+			}
+		}
+	}
+	return nil
+}
+
+func recordTypeOf(options storage.DNSOptions) string {
+	if options.RecordType == "" {
+		return "A"
+	}
+	return strings.ToUpper(options.RecordType)
+}
+
+// normalizeRecord normalizes expected value the same way resolved records
+// are normalized
+func normalizeRecord(recordType, value string) string {
+	switch recordType {
+	case "A", "AAAA":
+		if ip := net.ParseIP(value); ip != nil {
+			return ip.String()
+		}
+	case "CNAME", "MX":
+		return normalizeName(value)
+	}
+	return value
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+func formatRecords(records []string) string {
+	quoted := make([]string, 0, len(records))
+	for _, record := range records {
+		quoted = append(quoted, strconv.Quote(record))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func containsString(values []string, expected string) bool {
+	for _, value := range values {
+		if value == expected {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package checker
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// DNS record types and class used by the test DNS server
+const (
+	dnsTypeA     = 1
+	dnsTypeCNAME = 5
+	dnsTypeMX    = 15
+	dnsTypeTXT   = 16
+	dnsTypeAAAA  = 28
+	dnsClassIN   = 1
+)
+
+// dnsRecord a resource record served by the test DNS server, data is the
+// encoded RDATA
+type dnsRecord struct {
+	recordType uint16
+	data       []byte
+}
+
+// dnsQuestion key of records served by the test DNS server
+type dnsQuestion struct {
+	name       string
+	recordType uint16
+}
+
+// startDNSServer starts a tiny DNS server over UDP answering with records,
+// unknown names are answered with NXDOMAIN
+func startDNSServer(t *testing.T, records map[dnsQuestion][]dnsRecord) net.PacketConn {
+	connection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, address, err := connection.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if response := answerDNSQuery(buffer[:n], records); response != nil {
+				connection.WriteTo(response, address)
+			}
+		}
+	}()
+	return connection
+}
+
+// answerDNSQuery builds response of the first question of query
+func answerDNSQuery(query []byte, records map[dnsQuestion][]dnsRecord) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+	// question starts right after the header: labels, type and class
+	end := 12
+	var labels []string
+	for end < len(query) && query[end] != 0 {
+		length := int(query[end])
+		if end+1+length > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[end+1:end+1+length]))
+		end += 1 + length
+	}
+	end += 5
+	if end > len(query) {
+		return nil
+	}
+	question := dnsQuestion{
+		name:       strings.ToLower(strings.Join(labels, ".")),
+		recordType: binary.BigEndian.Uint16(query[end-4 : end-2]),
+	}
+	answers, found := records[question]
+	if !found {
+		// the name may exist with records of other types only
+		for known := range records {
+			if known.name == question.name {
+				found = true
+			}
+		}
+	}
+
+	response := make([]byte, 12, 512)
+	copy(response[0:2], query[0:2])
+	flags := uint16(0x8180)
+	if !found {
+		flags |= 3
+	}
+	binary.BigEndian.PutUint16(response[2:4], flags)
+	binary.BigEndian.PutUint16(response[4:6], 1)
+	binary.BigEndian.PutUint16(response[6:8], uint16(len(answers)))
+	response = append(response, query[12:end]...)
+	for _, answer := range answers {
+		// name is a pointer to the question name at offset 12
+		response = append(response, 0xC0, 12)
+		response = binary.BigEndian.AppendUint16(response, answer.recordType)
+		response = binary.BigEndian.AppendUint16(response, dnsClassIN)
+		response = binary.BigEndian.AppendUint32(response, 60)
+		response = binary.BigEndian.AppendUint16(response, uint16(len(answer.data)))
+		response = append(response, answer.data...)
+	}
+	return response
+}
+
+// encodeDNSName encodes domain name as uncompressed sequence of labels
+func encodeDNSName(name string) []byte {
+	var encoded []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		encoded = append(encoded, byte(len(label)))
+		encoded = append(encoded, label...)
+	}
+	return append(encoded, 0)
+}
+
+func TestDNSCheck(t *testing.T) {
+	// arrange
+	server := startDNSServer(t, map[dnsQuestion][]dnsRecord{
+		{"www.gohealthz.test", dnsTypeA}: {
+			{dnsTypeA, net.ParseIP("192.0.2.10").To4()},
+			{dnsTypeA, net.ParseIP("192.0.2.11").To4()},
+		},
+		{"www.gohealthz.test", dnsTypeAAAA}:    {{dnsTypeAAAA, net.ParseIP("2001:db8::10")}},
+		{"alias.gohealthz.test", dnsTypeCNAME}: {{dnsTypeCNAME, encodeDNSName("www.gohealthz.test")}},
+		{"gohealthz.test", dnsTypeTXT}:         {{dnsTypeTXT, append([]byte{15}, "v=spf1 -all ok."...)}},
+		{"gohealthz.test", dnsTypeMX}:          {{dnsTypeMX, append([]byte{0, 10}, encodeDNSName("mail.gohealthz.test")...)}},
+	})
+	defer server.Close()
+	checker := &DNS{}
+	checkTests := []struct {
+		testName        string
+		name            string
+		options         storage.DNSOptions
+		expected        storage.State
+		failedAssertion string
+	}{
+		{"A", "www.gohealthz.test", storage.DNSOptions{Expected: []string{"192.0.2.11"}}, storage.StateUp, ""},
+		{"A any record", "www.gohealthz.test", storage.DNSOptions{}, storage.StateUp, ""},
+		{"A hijacked", "www.gohealthz.test", storage.DNSOptions{Expected: []string{"192.0.2.99"}}, storage.StateDown, `dns A contains "192.0.2.99"`},
+		{"AAAA", "www.gohealthz.test", storage.DNSOptions{RecordType: "AAAA", Expected: []string{"2001:db8:0::10"}}, storage.StateUp, ""},
+		{"CNAME", "alias.gohealthz.test", storage.DNSOptions{RecordType: "cname", Expected: []string{"WWW.gohealthz.test."}}, storage.StateUp, ""},
+		{"TXT", "gohealthz.test", storage.DNSOptions{RecordType: "TXT", Expected: []string{"v=spf1 -all ok."}}, storage.StateUp, ""},
+		{"MX", "gohealthz.test", storage.DNSOptions{RecordType: "MX", Expected: []string{"mail.gohealthz.test"}}, storage.StateUp, ""},
+		{"MX misconfigured", "gohealthz.test", storage.DNSOptions{RecordType: "MX", Expected: []string{"mx.gohealthz.test"}}, storage.StateDown, `dns MX contains "mx.gohealthz.test"`},
+		{"NXDOMAIN", "missing.gohealthz.test", storage.DNSOptions{}, storage.StateDown, ""},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			tt.options.Resolver = server.LocalAddr().String()
+
+			// action
+			result := checker.Check(ctx, storage.Website{CheckType: storage.CheckDNS, URL: tt.name, DNS: tt.options})
+
+			// acceptance
+			if result.State != tt.expected {
+				t.Errorf("expected state %s, got %s (error: %s)", tt.expected, result.State, result.Error)
+			}
+			if result.FailedAssertion != tt.failedAssertion {
+				t.Errorf("expected failed assertion %q, got %q", tt.failedAssertion, result.FailedAssertion)
+			}
+		})
+	}
+}
+
+func TestDNSValidate(t *testing.T) {
+	// arrange
+	validateTests := []struct {
+		testName string
+		website  storage.Website
+		valid    bool
+	}{
+		{"domain name", storage.Website{URL: "example.com"}, true},
+		{"fully qualified", storage.Website{URL: "example.com.", DNS: storage.DNSOptions{RecordType: "TXT", Resolver: "1.1.1.1:53"}}, true},
+		{"URL", storage.Website{URL: "https://example.com"}, false},
+		{"unsupported record type", storage.Website{URL: "example.com", DNS: storage.DNSOptions{RecordType: "SRV"}}, false},
+		{"resolver without port", storage.Website{URL: "example.com", DNS: storage.DNSOptions{Resolver: "1.1.1.1"}}, false},
+		{"IPv6 expected of A record", storage.Website{URL: "example.com", DNS: storage.DNSOptions{Expected: []string{"::1"}}}, false},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			err := (&DNS{}).Validate(tt.website)
+
+			// acceptance
+			if (err == nil) != tt.valid {
+				t.Errorf("expected valid to be %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
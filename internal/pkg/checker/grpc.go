@@ -0,0 +1,182 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/assertion"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// grpcHealthCheckPath path of Check method of gRPC Health Checking Protocol
+const grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+
+// grpcServingStatus values of HealthCheckResponse.ServingStatus
+var grpcServingStatus = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+// GRPC checks a website by calling Check of gRPC Health Checking Protocol
+// (grpc.health.v1.Health). URL of the website is either grpc://host:port
+// (HTTP/2 without TLS) or grpcs://host:port (HTTP/2 over TLS), and the
+// website is up only when the service is SERVING
+type GRPC struct {
+	// TLSConfig configuration of TLS connections of grpcs URLs, default
+	// configuration is used when it is nil
+	TLSConfig *tls.Config
+}
+
+// Validate implements Checker, URL of the website must be in form of
+// grpc://host:port or grpcs://host:port
+func (checker *GRPC) Validate(website storage.Website) error {
+	parsedURL, err := url.Parse(website.URL)
+	if err != nil {
+		return fmt.Errorf("URL must be in form of grpc://host:port: %v", err)
+	}
+	if (parsedURL.Scheme != "grpc" && parsedURL.Scheme != "grpcs") || parsedURL.Hostname() == "" || parsedURL.Port() == "" {
+		return fmt.Errorf("URL must be in form of grpc://host:port or grpcs://host:port, got %q", website.URL)
+	}
+	return nil
+}
+
+// Check implements Checker
+func (checker *GRPC) Check(ctx context.Context, website storage.Website) storage.CheckResult {
+	result := storage.CheckResult{State: storage.StateDown}
+	started := time.Now()
+	status, err := checker.call(ctx, website)
+	result.Latency = time.Since(started)
+	if err == nil && status != "SERVING" {
+		err = &assertion.Error{Assertion: "grpc status SERVING", Reason: fmt.Sprintf("got %s", status)}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.FailedAssertion = assertion.FailedAssertion(err)
+		return result
+	}
+	result.State = storage.StateUp
+	return result
+}
+
+// call calls Check of the health service and returns the serving status
+func (checker *GRPC) call(ctx context.Context, website storage.Website) (string, error) {
+	parsedURL, err := url.Parse(website.URL)
+	if err != nil {
+		return "", err
+	}
+	protocols := new(http.Protocols)
+	endpoint := url.URL{Scheme: "https", Host: parsedURL.Host, Path: grpcHealthCheckPath}
+	if parsedURL.Scheme == "grpc" {
+		protocols.SetUnencryptedHTTP2(true)
+		endpoint.Scheme = "http"
+	} else {
+		protocols.SetHTTP2(true)
+	}
+	transport := &http.Transport{Protocols: protocols, TLSClientConfig: checker.TLSConfig}
+	defer transport.CloseIdleConnections()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(encodeGRPCHealthCheckRequest(website.GRPC.Service)))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/grpc")
+	request.Header.Set("TE", "trailers")
+	response, err := transport.RoundTrip(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP status code %d", response.StatusCode)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, assertion.DefaultMaxReadBodySize))
+	if err != nil {
+		return "", err
+	}
+	// status is sent as trailer, or as header when there is no message
+	grpcStatus := response.Trailer.Get("Grpc-Status")
+	grpcMessage := response.Trailer.Get("Grpc-Message")
+	if grpcStatus == "" {
+		grpcStatus = response.Header.Get("Grpc-Status")
+		grpcMessage = response.Header.Get("Grpc-Message")
+	}
+	if grpcStatus != "0" {
+		return "", fmt.Errorf("gRPC call failed with status %s: %s", grpcStatus, grpcMessage)
+	}
+	return decodeGRPCHealthCheckResponse(body)
+}
+
+// encodeGRPCHealthCheckRequest encodes HealthCheckRequest{service} as
+// length-prefixed gRPC message
+func encodeGRPCHealthCheckRequest(service string) []byte {
+	var message []byte
+	if service != "" {
+		// field 1 (service), wire type 2 (length-delimited)
+		message = append(message, 0x0A)
+		message = binary.AppendUvarint(message, uint64(len(service)))
+		message = append(message, service...)
+	}
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// decodeGRPCHealthCheckResponse decodes serving status of length-prefixed
+// HealthCheckResponse message
+func decodeGRPCHealthCheckResponse(frame []byte) (string, error) {
+	if len(frame) < 5 {
+		return "", fmt.Errorf("invalid gRPC response: message is missing")
+	}
+	if frame[0] != 0 {
+		return "", fmt.Errorf("invalid gRPC response: compressed message is not supported")
+	}
+	length := binary.BigEndian.Uint32(frame[1:5])
+	if uint32(len(frame)-5) < length {
+		return "", fmt.Errorf("invalid gRPC response: message is truncated")
+	}
+	message := frame[5 : 5+length]
+	// status is zero (UNKNOWN) when the field is absent
+	var status uint64
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return "", fmt.Errorf("invalid gRPC response: malformed field")
+		}
+		message = message[n:]
+		field, wireType := key>>3, key&7
+		switch wireType {
+		case 0:
+			value, n := binary.Uvarint(message)
+			if n <= 0 {
+				return "", fmt.Errorf("invalid gRPC response: malformed varint")
+			}
+			message = message[n:]
+			if field == 1 {
+				status = value
+			}
+		case 2:
+			length, n := binary.Uvarint(message)
+			if n <= 0 || uint64(len(message)-n) < length {
+				return "", fmt.Errorf("invalid gRPC response: malformed field")
+			}
+			message = message[n+int(length):]
+		default:
+			return "", fmt.Errorf("invalid gRPC response: unsupported wire type %d", wireType)
+		}
+	}
+	name, ok := grpcServingStatus[status]
+	if !ok {
+		return fmt.Sprintf("status(%d)", status), nil
+	}
+	return name, nil
+}
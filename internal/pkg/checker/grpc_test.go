@@ -0,0 +1,149 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// grpcHealthServer serves gRPC Health Checking Protocol over HTTP/2 with
+// serving status of every service by its name
+type grpcHealthServer struct {
+	statuses map[string]uint64
+}
+
+func (server *grpcHealthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != grpcHealthCheckPath || r.Header.Get("Content-Type") != "application/grpc" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	frame, _ := ioutil.ReadAll(r.Body)
+	var service string
+	if len(frame) > 5 && frame[5] == 0x0A {
+		length, n := binary.Uvarint(frame[6:])
+		service = string(frame[6+n : 6+n+int(length)])
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	status, ok := server.statuses[service]
+	if !ok {
+		// trailers-only response of NOT_FOUND status
+		w.Header().Set("Grpc-Status", "5")
+		w.Header().Set("Grpc-Message", "unknown service")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Trailer", "Grpc-Status")
+	w.WriteHeader(http.StatusOK)
+	// HealthCheckResponse{status}: field 1, wire type 0 (varint)
+	message := binary.AppendUvarint([]byte{0x08}, status)
+	response := make([]byte, 5)
+	binary.BigEndian.PutUint32(response[1:], uint32(len(message)))
+	w.Write(append(response, message...))
+	w.Header().Set("Grpc-Status", "0")
+}
+
+func startGRPCHealthServer(statuses map[string]uint64) *httptest.Server {
+	server := httptest.NewUnstartedServer(&grpcHealthServer{statuses: statuses})
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	return server
+}
+
+func TestGRPCCheck(t *testing.T) {
+	// arrange
+	server := startGRPCHealthServer(map[string]uint64{
+		"":            1,
+		"billing":     1,
+		"inventory":   2,
+		"maintenance": 0,
+	})
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "http://")
+	checker := &GRPC{}
+	checkTests := []struct {
+		service         string
+		expected        storage.State
+		failedAssertion string
+	}{
+		{"", storage.StateUp, ""},
+		{"billing", storage.StateUp, ""},
+		{"inventory", storage.StateDown, "grpc status SERVING"},
+		{"maintenance", storage.StateDown, "grpc status SERVING"},
+		{"unknown", storage.StateDown, ""},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.service, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			// action
+			result := checker.Check(ctx, storage.Website{
+				CheckType: storage.CheckGRPC,
+				URL:       "grpc://" + address,
+				GRPC:      storage.GRPCOptions{Service: tt.service},
+			})
+
+			// acceptance
+			if result.State != tt.expected {
+				t.Errorf("expected state %s, got %s (error: %s)", tt.expected, result.State, result.Error)
+			}
+			if result.FailedAssertion != tt.failedAssertion {
+				t.Errorf("expected failed assertion %q, got %q", tt.failedAssertion, result.FailedAssertion)
+			}
+		})
+	}
+}
+
+func TestGRPCCheckOverTLS(t *testing.T) {
+	// arrange
+	server := httptest.NewUnstartedServer(&grpcHealthServer{statuses: map[string]uint64{"": 1}})
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	transport := server.Client().Transport.(*http.Transport)
+	checker := &GRPC{TLSConfig: &tls.Config{RootCAs: transport.TLSClientConfig.RootCAs}}
+
+	// action
+	result := checker.Check(context.Background(), storage.Website{URL: strings.Replace(server.URL, "https://", "grpcs://", 1)})
+
+	// acceptance
+	if result.State != storage.StateUp {
+		t.Errorf("expected state %s, got %s (error: %s)", storage.StateUp, result.State, result.Error)
+	}
+}
+
+func TestGRPCValidate(t *testing.T) {
+	// arrange
+	validateTests := []struct {
+		url   string
+		valid bool
+	}{
+		{"grpc://localhost:50051", true},
+		{"grpcs://api.example.com:443", true},
+		{"grpc://localhost", false},
+		{"http://localhost:50051", false},
+		{"localhost:50051", false},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.url, func(t *testing.T) {
+			// action
+			err := (&GRPC{}).Validate(storage.Website{URL: tt.url})
+
+			// acceptance
+			if (err == nil) != tt.valid {
+				t.Errorf("expected valid to be %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// Heartbeat checks a website that pings gohealthz instead of being probed.
+// The website is down once no ping arrives within its interval plus grace
+// since the latest ping (or since it started waiting for pings). The state is
+// unknown until the first ping or the first missed deadline
+type Heartbeat struct {
+	// Now source of the current time the deadline is compared to, time.Now
+	// is used when it is nil
+	Now func() time.Time
+}
+
+// Validate implements Checker, the expected period between two pings
+// (interval of the website) is required
+func (checker *Heartbeat) Validate(website storage.Website) error {
+	if website.Interval <= 0 {
+		return fmt.Errorf("interval (expected period between pings) is required")
+	}
+	if website.Heartbeat.Grace < 0 {
+		return fmt.Errorf("grace must not be negative, got %s", website.Heartbeat.Grace)
+	}
+	return nil
+}
+
+// Check implements Checker
+func (checker *Heartbeat) Check(ctx context.Context, website storage.Website) storage.CheckResult {
+	now := time.Now
+	if checker.Now != nil {
+		now = checker.Now
+	}
+	since, deadline := HeartbeatDeadline(website)
+	if now().After(deadline) {
+		message := fmt.Sprintf("no ping received since %s", since.Format(time.RFC3339))
+		if website.Heartbeat.LastPing.IsZero() {
+			message = fmt.Sprintf("no ping received since started waiting at %s", since.Format(time.RFC3339))
+		}
+		return storage.CheckResult{State: storage.StateDown, Error: message}
+	}
+	if website.Heartbeat.LastPing.IsZero() {
+		return storage.CheckResult{State: storage.StateUnknown}
+	}
+	return storage.CheckResult{State: storage.StateUp}
+}
+
+// HeartbeatDeadline returns the time the website is waiting for pings since
+// (the latest ping, or the time it started waiting), and the deadline after
+// which it is down unless another ping arrives
+func HeartbeatDeadline(website storage.Website) (since, deadline time.Time) {
+	since = website.Heartbeat.LastPing
+	if since.IsZero() {
+		since = website.Heartbeat.Started
+	}
+	return since, since.Add(website.Interval + website.Heartbeat.Grace)
+}
@@ -0,0 +1,86 @@
+package checker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+func TestHeartbeatCheck(t *testing.T) {
+	// arrange
+	now := time.Date(2019, 3, 8, 10, 0, 0, 0, time.UTC)
+	checkTests := []struct {
+		testName  string
+		heartbeat storage.Heartbeat
+		expected  storage.State
+	}{
+		{"waiting for first ping", storage.Heartbeat{Started: now.Add(-30 * time.Second)}, storage.StateUnknown},
+		{"first ping missed", storage.Heartbeat{Started: now.Add(-2 * time.Minute)}, storage.StateDown},
+		{"pinged in time", storage.Heartbeat{Started: now.Add(-time.Hour), LastPing: now.Add(-30 * time.Second)}, storage.StateUp},
+		{"late within grace", storage.Heartbeat{Started: now.Add(-time.Hour), LastPing: now.Add(-90 * time.Second), Grace: time.Minute}, storage.StateUp},
+		{"late beyond grace", storage.Heartbeat{Started: now.Add(-time.Hour), LastPing: now.Add(-150 * time.Second), Grace: time.Minute}, storage.StateDown},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			result := (&Heartbeat{Now: func() time.Time { return now }}).Check(context.Background(), storage.Website{
+				CheckType: storage.CheckHeartbeat,
+				Interval:  time.Minute,
+				Heartbeat: tt.heartbeat,
+			})
+
+			// acceptance
+			if result.State != tt.expected {
+				t.Errorf("expected state %s, got %s", tt.expected, result.State)
+			}
+			if tt.expected == storage.StateDown && result.Error == "" {
+				t.Errorf("expected missed ping to be reported")
+			}
+		})
+	}
+}
+
+func TestRegistryCheckKeepsUnknownState(t *testing.T) {
+	// arrange
+	website := storage.Website{
+		CheckType: storage.CheckHeartbeat,
+		Interval:  time.Minute,
+		Heartbeat: storage.Heartbeat{Started: time.Now()},
+	}
+
+	// action
+	result := NewRegistry(nil).Check(context.Background(), website)
+
+	// acceptance
+	if result.State != storage.StateUnknown || result.Healthy {
+		t.Errorf("expected unhealthy result of unknown state, got %s", result.State)
+	}
+}
+
+func TestHeartbeatValidate(t *testing.T) {
+	// arrange
+	validateTests := []struct {
+		testName string
+		website  storage.Website
+		valid    bool
+	}{
+		{"interval", storage.Website{Interval: time.Hour}, true},
+		{"missing interval", storage.Website{}, false},
+		{"negative grace", storage.Website{Interval: time.Hour, Heartbeat: storage.Heartbeat{Grace: -time.Minute}}, false},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			err := (&Heartbeat{}).Validate(tt.website)
+
+			// acceptance
+			if (err == nil) != tt.valid {
+				t.Errorf("expected valid to be %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/assertion"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// HTTP checks a website by sending its request (GET unless configured
+// otherwise) to its URL and following redirects according to its redirect
+// policy, the website is up when the final response meets every assertion of
+// the website
+type HTTP struct {
+	// Client client used to send requests, http.DefaultClient is used when
+	// it is nil. Timeout is applied per check through context
+	Client *http.Client
+}
+
+// Validate implements Checker, URL of the website must be an absolute URL
+func (checker *HTTP) Validate(website storage.Website) error {
+	if _, err := url.ParseRequestURI(website.URL); err != nil {
+		return fmt.Errorf("URL must be in form of absolute URL: %v", err)
+	}
+	if website.CertificateExpiryDays < 0 {
+		return fmt.Errorf("certificate expiry days must not be negative, got %d", website.CertificateExpiryDays)
+	}
+	if err := validateRequest(website.Request); err != nil {
+		return fmt.Errorf("invalid request: %v", err)
+	}
+	if err := validateRedirectPolicy(website.Redirects); err != nil {
+		return fmt.Errorf("invalid redirects: %v", err)
+	}
+	return assertion.Validate(website.Assertions)
+}
+
+// Check implements Checker
+func (checker *HTTP) Check(ctx context.Context, website storage.Website) storage.CheckResult {
+	client := checker.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	redirects := &redirectRecorder{policy: website.Redirects}
+	result := storage.CheckResult{State: storage.StateDown}
+	started := time.Now()
+	response, recorder, err := timedRequest(ctx, redirects.client(client), website)
+	result.Redirects = redirects.urls
+	var body []byte
+	if err == nil {
+		result.StatusCode = response.StatusCode
+		if response.TLS != nil {
+			// the client refuses invalid chains, so a received response
+			// has a valid chain unless verification is skipped
+			result.Certificate = newCertificate(*response.TLS, len(response.TLS.VerifiedChains) > 0)
+		}
+		body, err = assertion.ReadBody(response, website.Assertions)
+		response.Body.Close()
+	}
+	result.Latency = time.Since(started)
+	result.Timings = recorder.result()
+	if err == nil {
+		err = redirects.evaluate(response)
+	}
+	if err == nil {
+		err = assertion.Evaluate(website.Assertions, response, body)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.FailedAssertion = assertion.FailedAssertion(err)
+		return result
+	}
+	result.State = storage.StateUp
+	checkCertificateExpiry(&result, website, time.Now())
+	return result
+}
@@ -0,0 +1,195 @@
+package checker
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+func TestHTTPCheck(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(100 * time.Millisecond)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	checker := &HTTP{Client: server.Client()}
+	checkTests := []struct {
+		testName        string
+		path            string
+		assertions      storage.Assertions
+		timeout         time.Duration
+		expected        storage.State
+		failedAssertion string
+	}{
+		{"up", "/", storage.Assertions{}, 0, storage.StateUp, ""},
+		{"status code", "/error", storage.Assertions{}, 0, storage.StateDown, "status_codes [200]"},
+		{"body", "/", storage.Assertions{BodyContains: []string{"healthy"}}, 0, storage.StateDown, `body_contains "healthy"`},
+		{"timeout", "/slow", storage.Assertions{}, 20 * time.Millisecond, storage.StateDown, ""},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			// action
+			result := checker.Check(ctx, storage.Website{URL: server.URL + tt.path, Assertions: tt.assertions})
+
+			// acceptance
+			if result.State != tt.expected {
+				t.Errorf("expected state %s, got %s (error: %s)", tt.expected, result.State, result.Error)
+			}
+			if result.FailedAssertion != tt.failedAssertion {
+				t.Errorf("expected failed assertion %q, got %q", tt.failedAssertion, result.FailedAssertion)
+			}
+			if tt.expected == storage.StateDown && result.Error == "" {
+				t.Errorf("expected error of failed check")
+			}
+			if result.Latency <= 0 {
+				t.Errorf("expected latency to be measured")
+			}
+		})
+	}
+}
+
+func TestHTTPCheckWithRequest(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		username, password, _ := r.BasicAuth()
+		w.Write([]byte(r.Method + " " + r.Host + " " + r.Header.Get("X-Probe") + " " + string(body) + " " + username + ":" + password + " " + r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+	checker := &HTTP{Client: server.Client()}
+	checkTests := []struct {
+		testName string
+		request  storage.HTTPRequest
+		expected string
+	}{
+		{"default", storage.HTTPRequest{}, "GET 127.0.0.1"},
+		{"method and body", storage.HTTPRequest{Method: http.MethodPost, Body: `{"ping":true}`}, `POST 127.0.0.1:`},
+		{"body", storage.HTTPRequest{Method: http.MethodPost, Body: `{"ping":true}`}, ` {"ping":true} `},
+		{"headers", storage.HTTPRequest{Headers: map[string]string{"X-Probe": "gohealthz", "host": "status.example.com"}}, "GET status.example.com gohealthz"},
+		{"basic auth", storage.HTTPRequest{Auth: storage.Auth{Username: "admin", Password: "secret"}}, " admin:secret Basic "},
+		{"bearer token", storage.HTTPRequest{Auth: storage.Auth{BearerToken: "t0ken"}}, "Bearer t0ken"},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			result := checker.Check(context.Background(), storage.Website{
+				URL:        server.URL,
+				Request:    tt.request,
+				Assertions: storage.Assertions{BodyContains: []string{tt.expected}},
+			})
+
+			// acceptance
+			if result.State != storage.StateUp {
+				t.Errorf("expected state %s, got %s (error: %s)", storage.StateUp, result.State, result.Error)
+			}
+		})
+	}
+}
+
+func TestHTTPValidateRequest(t *testing.T) {
+	// arrange
+	validateTests := []struct {
+		testName string
+		request  storage.HTTPRequest
+		valid    bool
+	}{
+		{"default", storage.HTTPRequest{}, true},
+		{"custom method", storage.HTTPRequest{Method: "PURGE"}, true},
+		{"invalid method", storage.HTTPRequest{Method: "GET /"}, false},
+		{"invalid header", storage.HTTPRequest{Headers: map[string]string{"X Probe": "1"}}, false},
+		{"basic auth and bearer token", storage.HTTPRequest{Auth: storage.Auth{Username: "admin", BearerToken: "t0ken"}}, false},
+		{"authorization header and credentials", storage.HTTPRequest{Headers: map[string]string{"authorization": "Basic x"}, Auth: storage.Auth{BearerToken: "t0ken"}}, false},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			err := (&HTTP{}).Validate(storage.Website{URL: "http://example.com", Request: tt.request})
+
+			// acceptance
+			if (err == nil) != tt.valid {
+				t.Errorf("expected valid to be %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckWithRedirects(t *testing.T) {
+	// arrange
+	// /hop/N redirects N more times before landing on /final
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hop/{n}", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.PathValue("n"))
+		location := "/final"
+		if n > 1 {
+			location = "/hop/" + strconv.Itoa(n-1)
+		}
+		http.Redirect(w, r, location, http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	checker := &HTTP{Client: server.Client()}
+	checkTests := []struct {
+		testName          string
+		redirects         storage.RedirectPolicy
+		assertions        storage.Assertions
+		expected          storage.State
+		failedAssertion   string
+		expectedRedirects []string
+	}{
+		{"follow", storage.RedirectPolicy{}, storage.Assertions{}, storage.StateUp, "", []string{server.URL + "/hop/1", server.URL + "/final"}},
+		{"no follow", storage.RedirectPolicy{NoFollow: true}, storage.Assertions{StatusCodes: []storage.StatusCodeRange{{Min: 302, Max: 302}}}, storage.StateUp, "", nil},
+		{"max hops", storage.RedirectPolicy{MaxHops: 2}, storage.Assertions{}, storage.StateUp, "", []string{server.URL + "/hop/1", server.URL + "/final"}},
+		{"too many hops", storage.RedirectPolicy{MaxHops: 1}, storage.Assertions{}, storage.StateDown, "max_redirects 1", []string{server.URL + "/hop/1"}},
+		{"final URL", storage.RedirectPolicy{FinalURL: server.URL + "/final"}, storage.Assertions{}, storage.StateUp, "", []string{server.URL + "/hop/1", server.URL + "/final"}},
+		{"unexpected final URL", storage.RedirectPolicy{FinalURL: server.URL + "/login"}, storage.Assertions{}, storage.StateDown, `final_url "` + server.URL + `/login"`, []string{server.URL + "/hop/1", server.URL + "/final"}},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			result := checker.Check(context.Background(), storage.Website{
+				URL:        server.URL + "/hop/2",
+				Redirects:  tt.redirects,
+				Assertions: tt.assertions,
+			})
+
+			// acceptance
+			if result.State != tt.expected {
+				t.Errorf("expected state %s, got %s (error: %s)", tt.expected, result.State, result.Error)
+			}
+			if result.FailedAssertion != tt.failedAssertion {
+				t.Errorf("expected failed assertion %q, got %q", tt.failedAssertion, result.FailedAssertion)
+			}
+			if !reflect.DeepEqual(result.Redirects, tt.expectedRedirects) {
+				t.Errorf("expected redirects %v, got %v", tt.expectedRedirects, result.Redirects)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/assertion"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// redirectRecorder follows redirects of a website according to its policy
+// and records URL of every followed redirect
+type redirectRecorder struct {
+	policy storage.RedirectPolicy
+	// clientCheckRedirect CheckRedirect of the client, it is still applied
+	// before the policy
+	clientCheckRedirect func(*http.Request, []*http.Request) error
+	urls                []string
+	exceeded            bool
+}
+
+// client returns copy of client that follows redirects through the recorder
+func (recorder *redirectRecorder) client(client *http.Client) *http.Client {
+	recorder.clientCheckRedirect = client.CheckRedirect
+	redirectClient := *client
+	redirectClient.CheckRedirect = recorder.checkRedirect
+	return &redirectClient
+}
+
+// checkRedirect implements CheckRedirect of http.Client, the redirect
+// response is used as the final response once it must not be followed
+func (recorder *redirectRecorder) checkRedirect(request *http.Request, via []*http.Request) error {
+	if recorder.clientCheckRedirect != nil {
+		if err := recorder.clientCheckRedirect(request, via); err != nil {
+			return err
+		}
+	}
+	if recorder.policy.NoFollow {
+		return http.ErrUseLastResponse
+	}
+	// via holds the original request along with every followed redirect
+	if len(via) > maxRedirects(recorder.policy) {
+		recorder.exceeded = true
+		return http.ErrUseLastResponse
+	}
+	recorder.urls = append(recorder.urls, request.URL.String())
+	return nil
+}
+
+// evaluate returns assertion error when the website redirects more than
+// allowed, or the final response does not come from the expected URL
+func (recorder *redirectRecorder) evaluate(response *http.Response) error {
+	if recorder.exceeded {
+		limit := maxRedirects(recorder.policy)
+		return &assertion.Error{
+			Assertion: fmt.Sprintf("max_redirects %d", limit),
+			Reason:    fmt.Sprintf("website redirects more than %d times", limit),
+		}
+	}
+	finalURL := response.Request.URL.String()
+	if recorder.policy.FinalURL != "" && finalURL != recorder.policy.FinalURL {
+		return &assertion.Error{
+			Assertion: fmt.Sprintf("final_url %q", recorder.policy.FinalURL),
+			Reason:    fmt.Sprintf("got %q", finalURL),
+		}
+	}
+	return nil
+}
+
+// validateRedirectPolicy validates number of hops and final URL of policy
+func validateRedirectPolicy(policy storage.RedirectPolicy) error {
+	if policy.MaxHops < 0 {
+		return fmt.Errorf("max hops must not be negative, got %d", policy.MaxHops)
+	}
+	if policy.NoFollow && policy.MaxHops > 0 {
+		return fmt.Errorf("max hops can not be used along with no follow")
+	}
+	if policy.FinalURL != "" {
+		if _, err := url.ParseRequestURI(policy.FinalURL); err != nil {
+			return fmt.Errorf("final URL must be in form of absolute URL: %v", err)
+		}
+	}
+	return nil
+}
+
+func maxRedirects(policy storage.RedirectPolicy) int {
+	if policy.MaxHops > 0 {
+		return policy.MaxHops
+	}
+	return storage.DefaultMaxRedirects
+}
@@ -0,0 +1,79 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// newRequest builds HTTP request of the website from its method, headers,
+// body and credentials
+func newRequest(ctx context.Context, website storage.Website) (*http.Request, error) {
+	options := website.Request
+	method := options.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if options.Body != "" {
+		body = strings.NewReader(options.Body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, website.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range options.Headers {
+		if http.CanonicalHeaderKey(name) == "Host" {
+			request.Host = value
+			continue
+		}
+		request.Header.Set(name, value)
+	}
+	if options.Auth.Username != "" || options.Auth.Password != "" {
+		request.SetBasicAuth(options.Auth.Username, options.Auth.Password)
+	}
+	if options.Auth.BearerToken != "" {
+		request.Header.Set("Authorization", "Bearer "+options.Auth.BearerToken)
+	}
+	return request, nil
+}
+
+// validateRequest validates method, headers and credentials of request
+// options
+func validateRequest(options storage.HTTPRequest) error {
+	if options.Method != "" && !isToken(options.Method) {
+		return fmt.Errorf("invalid method %q", options.Method)
+	}
+	hasBasicAuth := options.Auth.Username != "" || options.Auth.Password != ""
+	hasCredentials := hasBasicAuth || options.Auth.BearerToken != ""
+	if hasBasicAuth && options.Auth.BearerToken != "" {
+		return fmt.Errorf("either basic authentication or bearer token can be used, not both")
+	}
+	for name := range options.Headers {
+		if !isToken(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if hasCredentials && http.CanonicalHeaderKey(name) == "Authorization" {
+			return fmt.Errorf("authorization header can not be used along with credentials")
+		}
+	}
+	return nil
+}
+
+// isToken reports whether value is a token of RFC 7230, the grammar of both
+// method and header name
+func isToken(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/assertion"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// maxBannerSize maximum number of bytes read from a TCP connection while
+// waiting for the expected banner
+const maxBannerSize = 64 << 10
+
+// TCP checks a website by opening TCP connection to its address (host:port
+// stored as URL of the website). When the website has bytes to send, they are
+// written once the connection is established, and when it expects bytes,
+// they must be received before the connection is closed
+type TCP struct {
+	// Dialer dialer used to open connections, zero value dialer is used when
+	// it is nil
+	Dialer *net.Dialer
+}
+
+// Validate implements Checker, URL of the website must be in form of
+// host:port
+func (checker *TCP) Validate(website storage.Website) error {
+	host, port, err := net.SplitHostPort(website.URL)
+	if err != nil {
+		return fmt.Errorf("address must be in form of host:port: %v", err)
+	}
+	if host == "" || port == "" || strings.Contains(host, "/") {
+		return fmt.Errorf("address must be in form of host:port, got %q", website.URL)
+	}
+	return nil
+}
+
+// Check implements Checker
+func (checker *TCP) Check(ctx context.Context, website storage.Website) storage.CheckResult {
+	dialer := checker.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	result := storage.CheckResult{State: storage.StateDown}
+	started := time.Now()
+	connection, err := dialer.DialContext(ctx, "tcp", website.URL)
+	result.Timings.Connect = time.Since(started)
+	if err == nil {
+		err = exchangeBanner(ctx, connection, website.TCP)
+		connection.Close()
+	}
+	result.Latency = time.Since(started)
+	if err != nil {
+		result.Error = err.Error()
+		result.FailedAssertion = assertion.FailedAssertion(err)
+		return result
+	}
+	result.State = storage.StateUp
+	return result
+}
+
+// exchangeBanner sends bytes of options to connection and waits until the
+// expected bytes are received
+func exchangeBanner(ctx context.Context, connection net.Conn, options storage.TCPOptions) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		connection.SetDeadline(deadline)
+	}
+	if options.Send != "" {
+		if _, err := connection.Write([]byte(options.Send)); err != nil {
+			return fmt.Errorf("unable to send: %v", err)
+		}
+	}
+	if options.Expect == "" {
+		return nil
+	}
+	var received []byte
+	buffer := make([]byte, 4096)
+	for len(received) < maxBannerSize {
+		n, err := connection.Read(buffer)
+		received = append(received, buffer[:n]...)
+		if bytes.Contains(received, []byte(options.Expect)) {
+			return nil
+		}
+		if err != nil {
+			return &assertion.Error{
+				Assertion: fmt.Sprintf("expect %q", options.Expect),
+				Reason:    fmt.Sprintf("got %q: %v", received, err),
+			}
+		}
+	}
+	return &assertion.Error{Assertion: fmt.Sprintf("expect %q", options.Expect), Reason: "banner not found"}
+}
@@ -0,0 +1,137 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// startBannerServer starts TCP server greeting every connection with banner,
+// and answering PING line with PONG
+func startBannerServer(t *testing.T, banner string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer connection.Close()
+				connection.Write([]byte(banner))
+				line, err := bufio.NewReader(connection).ReadString('\n')
+				if err == nil && line == "PING\n" {
+					connection.Write([]byte("PONG\n"))
+				}
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestTCPCheck(t *testing.T) {
+	// arrange
+	listener := startBannerServer(t, "220 ready\n")
+	defer listener.Close()
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	closedAddress := closedListener.Addr().String()
+	closedListener.Close()
+	checker := &TCP{}
+	checkTests := []struct {
+		testName        string
+		address         string
+		options         storage.TCPOptions
+		expected        storage.State
+		failedAssertion string
+	}{
+		{"connect", listener.Addr().String(), storage.TCPOptions{}, storage.StateUp, ""},
+		{"banner", listener.Addr().String(), storage.TCPOptions{Expect: "220"}, storage.StateUp, ""},
+		{"send and expect", listener.Addr().String(), storage.TCPOptions{Send: "PING\n", Expect: "PONG"}, storage.StateUp, ""},
+		{"unexpected banner", listener.Addr().String(), storage.TCPOptions{Expect: "SSH-2.0"}, storage.StateDown, `expect "SSH-2.0"`},
+		{"connection refused", closedAddress, storage.TCPOptions{}, storage.StateDown, ""},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			// action
+			result := checker.Check(ctx, storage.Website{CheckType: storage.CheckTCP, URL: tt.address, TCP: tt.options})
+
+			// acceptance
+			if result.State != tt.expected {
+				t.Errorf("expected state %s, got %s (error: %s)", tt.expected, result.State, result.Error)
+			}
+			if result.FailedAssertion != tt.failedAssertion {
+				t.Errorf("expected failed assertion %q, got %q", tt.failedAssertion, result.FailedAssertion)
+			}
+		})
+	}
+}
+
+func TestTCPCheckTimeoutWaitingForBanner(t *testing.T) {
+	// arrange
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		connection, err := listener.Accept()
+		if err == nil {
+			// never greet, keep the connection open until the test is done
+			defer connection.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// action
+	result := (&TCP{}).Check(ctx, storage.Website{URL: listener.Addr().String(), TCP: storage.TCPOptions{Expect: "220"}})
+
+	// acceptance
+	if result.State != storage.StateDown {
+		t.Errorf("expected state %s, got %s", storage.StateDown, result.State)
+	}
+	if result.Latency > 500*time.Millisecond {
+		t.Errorf("expected check to give up after timeout, took %s", result.Latency)
+	}
+}
+
+func TestTCPValidate(t *testing.T) {
+	// arrange
+	validateTests := []struct {
+		address string
+		valid   bool
+	}{
+		{"localhost:5432", true},
+		{"[::1]:6379", true},
+		{"localhost", false},
+		{":5432", false},
+		{"tcp://localhost:5432", false},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.address, func(t *testing.T) {
+			// action
+			err := (&TCP{}).Validate(storage.Website{URL: tt.address})
+
+			// acceptance
+			if (err == nil) != tt.valid {
+				t.Errorf("expected valid to be %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// TLS checks a website by opening TLS connection to its address (host:port
+// stored as URL of the website). The website is down when its certificate
+// chain is not valid, and degraded when its certificate expires soon
+type TLS struct {
+	// Dialer dialer used to open connections, zero value dialer is used when
+	// it is nil
+	Dialer *net.Dialer
+	// RootCAs certificate authorities trusted when verifying certificate
+	// chains, system roots are used when it is nil
+	RootCAs *x509.CertPool
+}
+
+// Validate implements Checker, URL of the website must be in form of
+// host:port
+func (checker *TLS) Validate(website storage.Website) error {
+	if err := (&TCP{}).Validate(website); err != nil {
+		return err
+	}
+	if website.CertificateExpiryDays < 0 {
+		return fmt.Errorf("certificate expiry days must not be negative, got %d", website.CertificateExpiryDays)
+	}
+	return nil
+}
+
+// Check implements Checker
+func (checker *TLS) Check(ctx context.Context, website storage.Website) storage.CheckResult {
+	netDialer := checker.Dialer
+	if netDialer == nil {
+		netDialer = &net.Dialer{}
+	}
+	result := storage.CheckResult{State: storage.StateDown}
+	host, _, err := net.SplitHostPort(website.URL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	dialer := &tls.Dialer{
+		NetDialer: netDialer,
+		// the chain is verified afterwards so an invalid certificate can
+		// still be recorded
+		Config: &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	started := time.Now()
+	connection, err := dialer.DialContext(ctx, "tcp", website.URL)
+	result.Latency = time.Since(started)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	connectionState := connection.(*tls.Conn).ConnectionState()
+	connection.Close()
+
+	now := time.Now()
+	verifyErr := verifyCertificate(connectionState, host, checker.RootCAs, now)
+	result.Certificate = newCertificate(connectionState, verifyErr == nil)
+	if verifyErr != nil {
+		result.Error = fmt.Sprintf("certificate chain is not valid: %v", verifyErr)
+		return result
+	}
+	result.State = storage.StateUp
+	checkCertificateExpiry(&result, website, now)
+	return result
+}
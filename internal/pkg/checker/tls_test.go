@@ -0,0 +1,223 @@
+package checker
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// testCA certificate authority issuing certificates for tests
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pool        *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gohealthz test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * day),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create CA certificate: %v", err)
+	}
+	certificate, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("unable to parse CA certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	return &testCA{certificate: certificate, key: key, pool: pool}
+}
+
+// issue issues certificate for localhost and 127.0.0.1 expiring at notAfter
+func (ca *testCA) issue(t *testing.T, notAfter time.Time) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key}
+}
+
+// startTLSServer starts TLS server presenting certificate, it only completes
+// handshake of every connection
+func startTLSServer(t *testing.T, certificate tls.Certificate) net.Listener {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}})
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connection.(*tls.Conn).Handshake()
+			connection.Close()
+		}
+	}()
+	return listener
+}
+
+func TestTLSCheck(t *testing.T) {
+	// arrange
+	ca := newTestCA(t)
+	checkTests := []struct {
+		testName   string
+		notAfter   time.Time
+		roots      *x509.CertPool
+		expiryDays int
+		expected   storage.State
+		chainValid bool
+	}{
+		{"valid", time.Now().Add(90 * day), ca.pool, 0, storage.StateUp, true},
+		{"expires soon", time.Now().Add(5*day + time.Hour), ca.pool, 0, storage.StateDegraded, true},
+		{"expires after own expiry days", time.Now().Add(5*day + time.Hour), ca.pool, 3, storage.StateUp, true},
+		{"expired", time.Now().Add(-time.Minute), ca.pool, 0, storage.StateDown, false},
+		{"untrusted", time.Now().Add(90 * day), x509.NewCertPool(), 0, storage.StateDown, false},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			listener := startTLSServer(t, ca.issue(t, tt.notAfter))
+			defer listener.Close()
+			_, port, _ := net.SplitHostPort(listener.Addr().String())
+			checker := &TLS{RootCAs: tt.roots}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			// action
+			result := checker.Check(ctx, storage.Website{
+				CheckType:             storage.CheckTLS,
+				URL:                   net.JoinHostPort("localhost", port),
+				CertificateExpiryDays: tt.expiryDays,
+			})
+
+			// acceptance
+			if result.State != tt.expected {
+				t.Errorf("expected state %s, got %s (error: %s)", tt.expected, result.State, result.Error)
+			}
+			if result.Certificate == nil {
+				t.Fatalf("expected certificate to be recorded")
+			}
+			if result.Certificate.ChainValid != tt.chainValid {
+				t.Errorf("expected chain valid to be %v", tt.chainValid)
+			}
+			if !result.Certificate.NotAfter.Equal(tt.notAfter.Truncate(time.Second)) {
+				t.Errorf("expected not after %s, got %s", tt.notAfter, result.Certificate.NotAfter)
+			}
+			if result.Certificate.Issuer != "CN=gohealthz test CA" {
+				t.Errorf("expected issuer CN=gohealthz test CA, got %s", result.Certificate.Issuer)
+			}
+			if len(result.Certificate.DNSNames) != 1 || result.Certificate.DNSNames[0] != "localhost" {
+				t.Errorf("expected DNS names [localhost], got %v", result.Certificate.DNSNames)
+			}
+		})
+	}
+}
+
+func TestTLSCheckWrongHost(t *testing.T) {
+	// arrange
+	ca := newTestCA(t)
+	listener := startTLSServer(t, ca.issue(t, time.Now().Add(90*day)))
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// action
+	result := (&TLS{RootCAs: ca.pool}).Check(ctx, storage.Website{URL: net.JoinHostPort("127.0.0.2", port)})
+
+	// acceptance
+	if result.State != storage.StateDown {
+		t.Errorf("expected state %s, got %s", storage.StateDown, result.State)
+	}
+}
+
+func TestHTTPCheckRecordsCertificate(t *testing.T) {
+	// arrange
+	ca := newTestCA(t)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{ca.issue(t, time.Now().Add(10*day+time.Hour))}}
+	server.StartTLS()
+	defer server.Close()
+	checker := &HTTP{Client: &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.pool}}}}
+
+	// action
+	result := checker.Check(context.Background(), storage.Website{URL: server.URL})
+
+	// acceptance
+	if result.State != storage.StateDegraded {
+		t.Errorf("expected state %s, got %s (error: %s)", storage.StateDegraded, result.State, result.Error)
+	}
+	if result.Error != "certificate expires in 10 days" {
+		t.Errorf("expected certificate expiry as reason, got %q", result.Error)
+	}
+	if result.Certificate == nil || !result.Certificate.ChainValid {
+		t.Errorf("expected valid certificate to be recorded, got %+v", result.Certificate)
+	}
+}
+
+func TestDaysToExpiry(t *testing.T) {
+	// arrange
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	expiryTests := []struct {
+		notAfter time.Time
+		expected int
+	}{
+		{now.Add(30 * day), 30},
+		{now.Add(30*day - time.Minute), 29},
+		{now.Add(time.Hour), 0},
+		{now.Add(-day), -1},
+	}
+
+	for _, tt := range expiryTests {
+		t.Run(tt.notAfter.String(), func(t *testing.T) {
+			// action
+			actual := DaysToExpiry(storage.Certificate{NotAfter: tt.notAfter}, now)
+
+			// acceptance
+			if actual != tt.expected {
+				t.Errorf("expected %d days, got %d", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// timingsRecorder records durations of every phase of a HTTP request through
+// httptrace hooks. Hooks may be called from different goroutines (e.g. when
+// several addresses are dialed at once), so every access is guarded
+type timingsRecorder struct {
+	mutex        sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timings      storage.Timings
+}
+
+func (recorder *timingsRecorder) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			recorder.mutex.Lock()
+			defer recorder.mutex.Unlock()
+			recorder.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			recorder.mutex.Lock()
+			defer recorder.mutex.Unlock()
+			recorder.timings.DNS = time.Since(recorder.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			recorder.mutex.Lock()
+			defer recorder.mutex.Unlock()
+			recorder.connectStart = time.Now()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			recorder.mutex.Lock()
+			defer recorder.mutex.Unlock()
+			if err == nil {
+				recorder.timings.Connect = time.Since(recorder.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			recorder.mutex.Lock()
+			defer recorder.mutex.Unlock()
+			recorder.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			recorder.mutex.Lock()
+			defer recorder.mutex.Unlock()
+			recorder.timings.TLSHandshake = time.Since(recorder.tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			recorder.mutex.Lock()
+			defer recorder.mutex.Unlock()
+			recorder.timings.FirstByte = time.Since(recorder.start)
+		},
+	}
+}
+
+func (recorder *timingsRecorder) result() storage.Timings {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return recorder.timings
+}
+
+// timedRequest sends request of the website to its URL with client and
+// records durations of every phase of the request until the response header
+// is received
+func timedRequest(ctx context.Context, client *http.Client, website storage.Website) (*http.Response, *timingsRecorder, error) {
+	recorder := &timingsRecorder{start: time.Now()}
+	ctx = httptrace.WithClientTrace(ctx, recorder.trace())
+	request, err := newRequest(ctx, website)
+	if err != nil {
+		return nil, recorder, err
+	}
+	response, err := client.Do(request)
+	return response, recorder, err
+}
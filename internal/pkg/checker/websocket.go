@@ -0,0 +1,255 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/assertion"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// webSocketGUID GUID appended to the key to compute Sec-WebSocket-Accept
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// opcodes of WebSocket frames
+const (
+	webSocketText  = 0x1
+	webSocketClose = 0x8
+	webSocketPing  = 0x9
+	webSocketPong  = 0xA
+)
+
+// maxWebSocketMessageSize maximum size of a message read from a WebSocket
+const maxWebSocketMessageSize = 1 << 20
+
+// WebSocket checks a website by performing WebSocket handshake with its URL
+// (ws:// or wss://). When the website has a message to send, it is sent once
+// the handshake is done and a reply must be received
+type WebSocket struct {
+	// Client client used for the handshake, http.DefaultClient is used when
+	// it is nil
+	Client *http.Client
+}
+
+// Validate implements Checker, URL of the website must be a ws:// or wss://
+// URL
+func (checker *WebSocket) Validate(website storage.Website) error {
+	parsedURL, err := url.Parse(website.URL)
+	if err != nil {
+		return fmt.Errorf("URL must be in form of ws:// or wss:// URL: %v", err)
+	}
+	if (parsedURL.Scheme != "ws" && parsedURL.Scheme != "wss") || parsedURL.Host == "" {
+		return fmt.Errorf("URL must be in form of ws:// or wss:// URL, got %q", website.URL)
+	}
+	return nil
+}
+
+// Check implements Checker
+func (checker *WebSocket) Check(ctx context.Context, website storage.Website) storage.CheckResult {
+	client := checker.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	result := storage.CheckResult{State: storage.StateDown}
+	started := time.Now()
+	connection, statusCode, err := webSocketHandshake(ctx, client, website.URL)
+	result.StatusCode = statusCode
+	if err == nil {
+		err = exchangeWebSocketMessage(ctx, connection, website.WebSocket)
+		connection.Close()
+	}
+	result.Latency = time.Since(started)
+	if err != nil {
+		result.Error = err.Error()
+		result.FailedAssertion = assertion.FailedAssertion(err)
+		return result
+	}
+	result.State = storage.StateUp
+	return result
+}
+
+// webSocketHandshake upgrades HTTP connection to rawURL into WebSocket, and
+// returns the connection along with status code of the handshake response
+func webSocketHandshake(ctx context.Context, client *http.Client, rawURL string) (io.ReadWriteCloser, int, error) {
+	endpoint, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, 0, err
+	}
+	endpoint.Scheme = strings.Replace(endpoint.Scheme, "ws", "http", 1)
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, 0, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", key)
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		response.Body.Close()
+		return nil, response.StatusCode, &assertion.Error{
+			Assertion: "websocket handshake",
+			Reason:    fmt.Sprintf("got status code %d", response.StatusCode),
+		}
+	}
+	connection, ok := response.Body.(io.ReadWriteCloser)
+	if !ok {
+		response.Body.Close()
+		return nil, response.StatusCode, fmt.Errorf("connection can not be upgraded")
+	}
+	if !strings.EqualFold(response.Header.Get("Upgrade"), "websocket") || response.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		connection.Close()
+		return nil, response.StatusCode, &assertion.Error{Assertion: "websocket handshake", Reason: "invalid upgrade response"}
+	}
+	return connection, response.StatusCode, nil
+}
+
+// exchangeWebSocketMessage sends message of options and waits for the reply
+func exchangeWebSocketMessage(ctx context.Context, connection io.ReadWriteCloser, options storage.WebSocketOptions) error {
+	if options.Send == "" && options.Expect == "" {
+		writeWebSocketFrame(connection, webSocketClose, nil)
+		return nil
+	}
+	// the upgraded connection ignores the context, so it is closed to
+	// unblock reading once the context is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			connection.Close()
+		case <-done:
+		}
+	}()
+
+	if options.Send != "" {
+		if err := writeWebSocketFrame(connection, webSocketText, []byte(options.Send)); err != nil {
+			return fmt.Errorf("unable to send message: %v", err)
+		}
+	}
+	reader := bufio.NewReader(connection)
+	for {
+		opcode, payload, err := readWebSocketFrame(reader)
+		if err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return fmt.Errorf("unable to receive reply: %v", err)
+		}
+		switch opcode {
+		case webSocketPing:
+			writeWebSocketFrame(connection, webSocketPong, payload)
+			continue
+		case webSocketPong:
+			continue
+		case webSocketClose:
+			return fmt.Errorf("connection closed by the website before reply")
+		}
+		writeWebSocketFrame(connection, webSocketClose, nil)
+		if options.Expect != "" && !strings.Contains(string(payload), options.Expect) {
+			return &assertion.Error{
+				Assertion: fmt.Sprintf("websocket reply contains %q", options.Expect),
+				Reason:    fmt.Sprintf("got %q", payload),
+			}
+		}
+		return nil
+	}
+}
+
+// webSocketAccept computes expected Sec-WebSocket-Accept of key
+func webSocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// writeWebSocketFrame writes a single final frame, masked since every frame
+// sent by a client must be masked (RFC 6455)
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// readWebSocketFrame reads a single frame, fragmented messages are not
+// supported since replies of health checks are expected to be small
+func readWebSocketFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	if header[0]&0x80 == 0 {
+		return 0, nil, fmt.Errorf("fragmented message is not supported")
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(r, extended); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(r, extended); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if length > maxWebSocketMessageSize {
+		return 0, nil, fmt.Errorf("message of %d bytes is too large", length)
+	}
+	var mask []byte
+	if header[1]&0x80 != 0 {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(r, mask); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if mask != nil {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
@@ -0,0 +1,139 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// webSocketEchoHandler accepts WebSocket handshake and echoes every text
+// message prefixed with "echo: ". Path /silent never replies and /plain
+// refuses the upgrade like a plain HTTP endpoint
+func webSocketEchoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/plain" || r.Header.Get("Upgrade") != "websocket" {
+		http.Error(w, "websocket only", http.StatusBadRequest)
+		return
+	}
+	connection, buffer, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer connection.Close()
+	fmt.Fprintf(buffer, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", webSocketAccept(r.Header.Get("Sec-WebSocket-Key")))
+	buffer.Flush()
+	reader := bufio.NewReader(buffer)
+	for {
+		opcode, payload, err := readWebSocketFrame(reader)
+		if err != nil || opcode == webSocketClose {
+			return
+		}
+		if r.URL.Path == "/silent" {
+			continue
+		}
+		writeServerFrame(connection, webSocketPing, nil)
+		writeServerFrame(connection, webSocketText, append([]byte("echo: "), payload...))
+	}
+}
+
+// writeServerFrame writes a single final unmasked frame as a server does,
+// payload must be shorter than 126 bytes
+func writeServerFrame(w io.Writer, opcode byte, payload []byte) error {
+	_, err := w.Write(append([]byte{0x80 | opcode, byte(len(payload))}, payload...))
+	return err
+}
+
+func TestWebSocketCheck(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(webSocketEchoHandler))
+	defer server.Close()
+	baseURL := strings.Replace(server.URL, "http://", "ws://", 1)
+	checker := &WebSocket{}
+	checkTests := []struct {
+		testName        string
+		path            string
+		options         storage.WebSocketOptions
+		expected        storage.State
+		failedAssertion string
+	}{
+		{"handshake", "/", storage.WebSocketOptions{}, storage.StateUp, ""},
+		{"echo", "/", storage.WebSocketOptions{Send: "ping", Expect: "echo: ping"}, storage.StateUp, ""},
+		{"any reply", "/", storage.WebSocketOptions{Send: "ping"}, storage.StateUp, ""},
+		{"unexpected reply", "/", storage.WebSocketOptions{Send: "ping", Expect: "pong"}, storage.StateDown, `websocket reply contains "pong"`},
+		{"no reply", "/silent", storage.WebSocketOptions{Send: "ping"}, storage.StateDown, ""},
+		{"plain HTTP", "/plain", storage.WebSocketOptions{}, storage.StateDown, "websocket handshake"},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			// action
+			result := checker.Check(ctx, storage.Website{CheckType: storage.CheckWebSocket, URL: baseURL + tt.path, WebSocket: tt.options})
+
+			// acceptance
+			if result.State != tt.expected {
+				t.Errorf("expected state %s, got %s (error: %s)", tt.expected, result.State, result.Error)
+			}
+			if result.FailedAssertion != tt.failedAssertion {
+				t.Errorf("expected failed assertion %q, got %q", tt.failedAssertion, result.FailedAssertion)
+			}
+			if result.Latency > time.Second {
+				t.Errorf("expected check to give up after timeout, took %s", result.Latency)
+			}
+		})
+	}
+}
+
+func TestWebSocketCheckOverTLS(t *testing.T) {
+	// arrange
+	server := httptest.NewTLSServer(http.HandlerFunc(webSocketEchoHandler))
+	defer server.Close()
+	checker := &WebSocket{Client: server.Client()}
+
+	// action
+	result := checker.Check(context.Background(), storage.Website{
+		URL:       strings.Replace(server.URL, "https://", "wss://", 1),
+		WebSocket: storage.WebSocketOptions{Send: "hello", Expect: "echo: hello"},
+	})
+
+	// acceptance
+	if result.State != storage.StateUp {
+		t.Errorf("expected state %s, got %s (error: %s)", storage.StateUp, result.State, result.Error)
+	}
+	if result.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("expected status code %d, got %d", http.StatusSwitchingProtocols, result.StatusCode)
+	}
+}
+
+func TestWebSocketFrameRoundTrip(t *testing.T) {
+	// arrange
+	sizes := []int{0, 125, 126, 70000}
+
+	for _, size := range sizes {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			payload := []byte(strings.Repeat("x", size))
+			var frame strings.Builder
+
+			// action
+			err := writeWebSocketFrame(&frame, webSocketText, payload)
+			opcode, actual, readErr := readWebSocketFrame(strings.NewReader(frame.String()))
+
+			// acceptance
+			if err != nil || readErr != nil {
+				t.Fatalf("unable to write or read frame: %v, %v", err, readErr)
+			}
+			if opcode != webSocketText || string(actual) != string(payload) {
+				t.Errorf("expected text frame of %d bytes, got opcode %d of %d bytes", size, opcode, len(actual))
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearch how far ahead Next looks for a matching time. Every valid
+// expression matches within 4 years (leap day), except expressions that never
+// match such as February 30th
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// descriptors shorthands of common expressions
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field bounds and names of a field of cron expression
+type field struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: []string{
+		"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec",
+	}}
+	// day of week 7 is Sunday as well, it is folded into 0 once parsed
+	dowField = field{name: "day of week", min: 0, max: 7, names: []string{
+		"sun", "mon", "tue", "wed", "thu", "fri", "sat",
+	}}
+)
+
+// Schedule parsed cron expression, every field is a set of allowed values
+// stored as bits
+type Schedule struct {
+	expression string
+	minute     uint64
+	hour       uint64
+	dom        uint64
+	month      uint64
+	dow        uint64
+	// domAny and dowAny whether day of month and day of week start with "*".
+	// Once both are restricted, a day matching either one of them matches
+	domAny bool
+	dowAny bool
+}
+
+// Parse parses standard 5 fields cron expression (minute, hour, day of month,
+// month and day of week), e.g. "*/15 9-17 * * mon-fri". Every field accepts
+// "*", values, ranges, lists and steps, month and day of week also accept
+// their 3 letters English names. Descriptors such as "@hourly" and "@daily"
+// are supported as well
+func Parse(expression string) (*Schedule, error) {
+	normalized := strings.TrimSpace(expression)
+	if descriptor, ok := descriptors[strings.ToLower(normalized)]; ok {
+		normalized = descriptor
+	}
+	fields := strings.Fields(normalized)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute, hour, day of month, month and day of week), got %d", len(fields))
+	}
+	schedule := &Schedule{
+		expression: expression,
+		domAny:     strings.HasPrefix(fields[2], "*"),
+		dowAny:     strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	if schedule.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if schedule.dom, err = domField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if schedule.dow, err = dowField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow = schedule.dow&^(1<<7) | 1
+	}
+	return schedule, nil
+}
+
+// String returns the expression the schedule is parsed from
+func (schedule *Schedule) String() string {
+	return schedule.expression
+}
+
+// Next returns the first time after t (at least one minute later, with zero
+// seconds) that matches the schedule, in location of t. Zero time is returned
+// when the schedule never matches
+func (schedule *Schedule) Next(t time.Time) time.Time {
+	after := t
+	limit := t.Add(maxSearch)
+	location := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, location)
+	for t.Before(limit) {
+		if !t.After(after) {
+			// wall clock within an hour repeated by daylight saving time
+			// may resolve to its earlier occurrence
+			t = t.Add(time.Minute)
+			continue
+		}
+		if !has(schedule.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, location)
+			continue
+		}
+		if !schedule.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, location)
+			continue
+		}
+		if !has(schedule.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, location)
+			continue
+		}
+		if !has(schedule.minute, t.Minute()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, location)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (schedule *Schedule) matchesDay(t time.Time) bool {
+	dom := has(schedule.dom, t.Day())
+	dow := has(schedule.dow, int(t.Weekday()))
+	if schedule.domAny || schedule.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(set uint64, value int) bool {
+	return set&(1<<uint(value)) != 0
+}
+
+// parse parses a field into set of allowed values, the field is a list of
+// "*", values or ranges, each one optionally followed by step (e.g. "*/5",
+// "1-10/2" or "5/15" which means from 5 to the max every 15)
+func (f field) parse(value string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q of %s", stepPart, f.name)
+			}
+		}
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = f.value(lowPart); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = f.value(highPart); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				high = f.max
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q of %s", rangePart, f.name)
+			}
+		}
+		for v := low; v <= high; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// value parses a single value of the field, either a number or a name
+func (f field) value(value string) (int, error) {
+	for index, name := range f.names {
+		if strings.EqualFold(value, name) {
+			return f.min + index, nil
+		}
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < f.min || number > f.max {
+		return 0, fmt.Errorf("invalid %s %q, must be between %d and %d", f.name, value, f.min, f.max)
+	}
+	return number, nil
+}
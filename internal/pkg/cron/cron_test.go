@@ -0,0 +1,107 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	// arrange
+	parseTests := []struct {
+		expression string
+		valid      bool
+	}{
+		{"* * * * *", true},
+		{"*/15 9-17 * * mon-fri", true},
+		{"0 0 1,15 jan,jul *", true},
+		{"5/10 * * * 7", true},
+		{"@daily", true},
+		{"@Hourly", true},
+		{"* * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"* * * * 8", false},
+		{"*/0 * * * *", false},
+		{"10-5 * * * *", false},
+		{"* * * * monday", false},
+		{"@every 5m", false},
+	}
+
+	for _, tt := range parseTests {
+		t.Run(tt.expression, func(t *testing.T) {
+			// action
+			_, err := Parse(tt.expression)
+
+			// acceptance
+			if (err == nil) != tt.valid {
+				t.Errorf("expected valid to be %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// arrange
+	// 2019-03-08 is Friday
+	from := time.Date(2019, 3, 8, 10, 7, 30, 0, time.UTC)
+	nextTests := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"* * * * *", time.Date(2019, 3, 8, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2019, 3, 8, 10, 15, 0, 0, time.UTC)},
+		{"5/10 * * * *", time.Date(2019, 3, 8, 10, 15, 0, 0, time.UTC)},
+		{"0 9-17 * * mon-fri", time.Date(2019, 3, 8, 11, 0, 0, 0, time.UTC)},
+		{"0 18 * * mon-fri", time.Date(2019, 3, 8, 18, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2019, 3, 11, 9, 0, 0, 0, time.UTC)},
+		{"30 2 * * sun", time.Date(2019, 3, 10, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2019, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// both days restricted: either the 1st or any Monday
+		{"0 0 1 * mon", time.Date(2019, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 feb *", time.Time{}},
+	}
+
+	for _, tt := range nextTests {
+		t.Run(tt.expression, func(t *testing.T) {
+			schedule, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("unable to parse expression: %v", err)
+			}
+
+			// action
+			actual := schedule.Next(from)
+
+			// acceptance
+			if !actual.Equal(tt.expected) {
+				t.Errorf("expected next time %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestScheduleNextUsesLocationOfTime(t *testing.T) {
+	// arrange
+	location := time.FixedZone("UTC+7", 7*60*60)
+	schedule, err := Parse("0 9 * * *")
+	if err != nil {
+		t.Fatalf("unable to parse expression: %v", err)
+	}
+
+	// action
+	actual := schedule.Next(time.Date(2019, 3, 8, 0, 0, 0, 0, time.UTC))
+
+	// acceptance
+	expected := time.Date(2019, 3, 8, 9, 0, 0, 0, time.UTC)
+	if !actual.Equal(expected) {
+		t.Errorf("expected next time %s, got %s", expected, actual)
+	}
+	actual = schedule.Next(time.Date(2019, 3, 8, 0, 0, 0, 0, time.UTC).In(location))
+	expected = time.Date(2019, 3, 8, 9, 0, 0, 0, location)
+	if !actual.Equal(expected) {
+		t.Errorf("expected next time %s, got %s", expected, actual)
+	}
+}
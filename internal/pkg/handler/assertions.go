@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"github.com/ajiyakin/gohealthz/internal/pkg/assertion"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// assertionsRequest assertions of a website as accepted by POST /website and
+// returned by GET /website
+type assertionsRequest struct {
+	// StatusCodes allowed status codes, either a single status code (e.g.
+	// "204") or a range of status codes (e.g. "200-299")
+	StatusCodes     []string          `json:"status_codes,omitempty"`
+	BodyContains    []string          `json:"body_contains,omitempty"`
+	BodyNotContains []string          `json:"body_not_contains,omitempty"`
+	BodyMatches     []string          `json:"body_matches,omitempty"`
+	BodyNotMatches  []string          `json:"body_not_matches,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	MaxBodySize     int64             `json:"max_body_size,omitempty"`
+	// JSON JSONPath-style assertions on JSON body (e.g. `$.status == "ok"`)
+	JSON []string `json:"json,omitempty"`
+}
+
+// parseAssertions converts and validates assertions of the request. Nil
+// request results in default assertions
+func parseAssertions(request *assertionsRequest) (storage.Assertions, error) {
+	if request == nil {
+		return storage.Assertions{}, nil
+	}
+	assertions := storage.Assertions{
+		BodyContains:    request.BodyContains,
+		BodyNotContains: request.BodyNotContains,
+		BodyMatches:     request.BodyMatches,
+		BodyNotMatches:  request.BodyNotMatches,
+		Headers:         request.Headers,
+		MaxBodySize:     request.MaxBodySize,
+		JSON:            request.JSON,
+	}
+	for _, value := range request.StatusCodes {
+		statusCodeRange, err := assertion.ParseStatusCodeRange(value)
+		if err != nil {
+			return storage.Assertions{}, err
+		}
+		assertions.StatusCodes = append(assertions.StatusCodes, statusCodeRange)
+	}
+	if err := assertion.Validate(assertions); err != nil {
+		return storage.Assertions{}, err
+	}
+	return assertions, nil
+}
+
+// newAssertionsResponse converts assertions of a website to its response
+// form. Nil is returned for default assertions so they are omitted
+func newAssertionsResponse(assertions storage.Assertions) *assertionsRequest {
+	response := assertionsRequest{
+		BodyContains:    assertions.BodyContains,
+		BodyNotContains: assertions.BodyNotContains,
+		BodyMatches:     assertions.BodyMatches,
+		BodyNotMatches:  assertions.BodyNotMatches,
+		Headers:         assertions.Headers,
+		MaxBodySize:     assertions.MaxBodySize,
+		JSON:            assertions.JSON,
+	}
+	for _, statusCodeRange := range assertions.StatusCodes {
+		response.StatusCodes = append(response.StatusCodes, assertion.FormatStatusCodeRange(statusCodeRange))
+	}
+	if len(response.StatusCodes) == 0 && len(response.BodyContains) == 0 && len(response.BodyNotContains) == 0 &&
+		len(response.BodyMatches) == 0 && len(response.BodyNotMatches) == 0 && len(response.Headers) == 0 &&
+		response.MaxBodySize == 0 && len(response.JSON) == 0 {
+		return nil
+	}
+	return &response
+}
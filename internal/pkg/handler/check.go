@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// WebsiteChecker checks a website on demand, regardless of its schedule, and
+// stores the result. It is implemented by updater.Updater so on-demand checks
+// run exactly like the scheduled ones
+type WebsiteChecker interface {
+	CheckWebsite(ctx context.Context, websiteID string) (storage.CheckResult, error)
+}
+
+// NewWebsiteCheckHandler initilize and get handler for checking a website on
+// demand (POST). The website ID is taken from {id} path value, the check runs
+// synchronously and its result is returned
+func NewWebsiteCheckHandler(websiteChecker WebsiteChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			checkWebsite(w, r, websiteChecker)
+			return
+		}
+		log.Printf("%s - method %s is not allowed", r.URL.Path, r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func checkWebsite(w http.ResponseWriter, r *http.Request, websiteChecker WebsiteChecker) {
+	websiteID := r.PathValue("id")
+	result, err := websiteChecker.CheckWebsite(r.Context(), websiteID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			http.Error(w, "website not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("unable to check website with id: %s: %v", websiteID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	responseBody := newCheckResultResponse(result)
+	if err = json.NewEncoder(w).Encode(&responseBody); err != nil {
+		log.Printf("unable to encode check result to response writter: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("successfully check website with id: %s on demand", websiteID)
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// websiteCheckerFunc adapts a function into WebsiteChecker
+type websiteCheckerFunc func(ctx context.Context, websiteID string) (storage.CheckResult, error)
+
+func (f websiteCheckerFunc) CheckWebsite(ctx context.Context, websiteID string) (storage.CheckResult, error) {
+	return f(ctx, websiteID)
+}
+
+func TestCheckWebsiteOnDemand(t *testing.T) {
+	// arrange
+	checked := time.Date(2019, 3, 8, 12, 0, 0, 0, time.UTC)
+	websiteChecker := websiteCheckerFunc(func(ctx context.Context, websiteID string) (storage.CheckResult, error) {
+		if websiteID != "1234" {
+			return storage.CheckResult{}, storage.ErrNotFound
+		}
+		return storage.CheckResult{
+			WebsiteID:       websiteID,
+			Time:            checked,
+			State:           storage.StateDown,
+			StatusCode:      http.StatusServiceUnavailable,
+			Latency:         120 * time.Millisecond,
+			FailedAssertion: "status_codes [200]",
+			Attempts:        2,
+		}, nil
+	})
+	checkTests := []struct {
+		websiteID    string
+		method       string
+		expectedCode int
+	}{
+		{"1234", http.MethodPost, http.StatusOK},
+		{"unknown", http.MethodPost, http.StatusNotFound},
+		{"1234", http.MethodGet, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.websiteID+" "+tt.method, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, "http://localhost:8080/website/"+tt.websiteID+"/check", nil)
+			request.SetPathValue("id", tt.websiteID)
+			responseRecorder := httptest.NewRecorder()
+
+			// action
+			handlerFunc := NewWebsiteCheckHandler(websiteChecker)
+			handlerFunc(responseRecorder, request)
+
+			// acceptance
+			if responseRecorder.Code != tt.expectedCode {
+				t.Fatalf("expected response code %d, got %d", tt.expectedCode, responseRecorder.Code)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			var responseBody getCheckResultResponse
+			if err := json.NewDecoder(responseRecorder.Body).Decode(&responseBody); err != nil {
+				t.Errorf("unable to decode response body: %v", err)
+			}
+			if !responseBody.Time.Equal(checked) || responseBody.State != "down" || responseBody.StatusCode != http.StatusServiceUnavailable ||
+				responseBody.LatencyMS != 120 || responseBody.FailedAssertion != "status_codes [200]" || responseBody.Attempts != 2 {
+				t.Errorf("unexpected check result: %#v", responseBody)
+			}
+		})
+	}
+}
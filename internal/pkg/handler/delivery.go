@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/notifier"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+type getDeliveryResponse struct {
+	Notification notificationResponse `json:"notification"`
+	Webhook      string               `json:"webhook"`
+	Time         time.Time            `json:"time"`
+	Attempts     int                  `json:"attempts"`
+	// StatusCode HTTP status code of the last attempt, omitted when there was
+	// no response
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Delivered  bool   `json:"delivered"`
+}
+
+type notificationResponse struct {
+	WebsiteID string    `json:"website_id"`
+	URL       string    `json:"url"`
+	OldState  string    `json:"old_state"`
+	NewState  string    `json:"new_state"`
+	LastError string    `json:"last_error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewDeliveryHandler initilize and get handler for retrieving delivery log of
+// notifications (GET), ordered from the latest delivery. The log can be
+// filtered by website with optional `website_id` query parameter
+func NewDeliveryHandler(database storage.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			getDeliveries(w, r, database)
+			return
+		}
+		log.Printf("%s - method %s is not allowed", r.URL.Path, r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func getDeliveries(w http.ResponseWriter, r *http.Request, database storage.Database) {
+	deliveries, err := database.GetDeliveries()
+	if err != nil {
+		log.Printf("unable to get delivery log from database: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	websiteID := r.URL.Query().Get("website_id")
+	responseBody := make([]getDeliveryResponse, 0, len(deliveries))
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		delivery := deliveries[i]
+		if websiteID != "" && delivery.Notification.WebsiteID != websiteID {
+			continue
+		}
+		responseBody = append(responseBody, getDeliveryResponse{
+			Notification: notificationResponse{
+				WebsiteID: delivery.Notification.WebsiteID,
+				URL:       storage.RedactURL(delivery.Notification.URL),
+				OldState:  string(delivery.Notification.OldState),
+				NewState:  string(delivery.Notification.NewState),
+				LastError: delivery.Notification.LastError,
+				Timestamp: delivery.Notification.Time,
+			},
+			Webhook:    notifier.RedactWebhookURL(delivery.Webhook),
+			Time:       delivery.Time,
+			Attempts:   delivery.Attempts,
+			StatusCode: delivery.StatusCode,
+			Error:      delivery.Error,
+			Delivered:  delivery.Delivered,
+		})
+	}
+	w.Header().Add("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(&responseBody); err != nil {
+		log.Printf("unable to encode delivery log to response writter: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+func TestGetDeliveries(t *testing.T) {
+	// arrange
+	start := time.Date(2019, 3, 8, 12, 0, 0, 0, time.UTC)
+	database := storage.NewInMemoryDatabase()
+	deliveries := []storage.Delivery{
+		{
+			Notification: storage.Notification{WebsiteID: "1234", URL: "https://example.com", OldState: storage.StateUp, NewState: storage.StateDown, LastError: "connection refused", Time: start},
+			Webhook:      "https://hooks.example.com/services/secret-token",
+			Time:         start.Add(time.Second),
+			Attempts:     3,
+			StatusCode:   http.StatusBadGateway,
+			Error:        "unexpected status code 502",
+		},
+		{
+			Notification: storage.Notification{WebsiteID: "5678", URL: "https://example.org", OldState: storage.StateUp, NewState: storage.StateDegraded, Time: start.Add(time.Minute)},
+			Webhook:      "https://hooks.example.com",
+			Time:         start.Add(time.Minute),
+			Attempts:     1,
+			StatusCode:   http.StatusOK,
+			Delivered:    true,
+		},
+	}
+	for _, delivery := range deliveries {
+		if err := database.SaveDelivery(delivery); err != nil {
+			t.Fatalf("unable to save delivery: %v", err)
+		}
+	}
+	deliveryTests := []struct {
+		query           string
+		expectedWebsite []string
+	}{
+		{"", []string{"5678", "1234"}},
+		{"?website_id=1234", []string{"1234"}},
+		{"?website_id=unknown", []string{}},
+	}
+
+	for _, tt := range deliveryTests {
+		t.Run(tt.query, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "http://localhost:8080/webhook/deliveries"+tt.query, nil)
+			responseRecorder := httptest.NewRecorder()
+
+			// action
+			handlerFunc := NewDeliveryHandler(database)
+			handlerFunc(responseRecorder, request)
+
+			// acceptance
+			if responseRecorder.Code != http.StatusOK {
+				t.Fatalf("expected response code %d, got %d", http.StatusOK, responseRecorder.Code)
+			}
+			var responseBody []getDeliveryResponse
+			if err := json.NewDecoder(responseRecorder.Body).Decode(&responseBody); err != nil {
+				t.Fatalf("unable to decode response body: %v", err)
+			}
+			if len(responseBody) != len(tt.expectedWebsite) {
+				t.Fatalf("expected %d deliveries, got %d", len(tt.expectedWebsite), len(responseBody))
+			}
+			for i, websiteID := range tt.expectedWebsite {
+				if responseBody[i].Notification.WebsiteID != websiteID {
+					t.Errorf("expected delivery %d of website %s, got %s", i, websiteID, responseBody[i].Notification.WebsiteID)
+				}
+			}
+		})
+	}
+
+	// failed delivery is reported with its reason
+	request := httptest.NewRequest(http.MethodGet, "http://localhost:8080/webhook/deliveries?website_id=1234", nil)
+	responseRecorder := httptest.NewRecorder()
+	NewDeliveryHandler(database)(responseRecorder, request)
+	var responseBody []getDeliveryResponse
+	if err := json.NewDecoder(responseRecorder.Body).Decode(&responseBody); err != nil || len(responseBody) != 1 {
+		t.Fatalf("unable to decode response body: %v", err)
+	}
+	failed := responseBody[0]
+	if failed.Delivered || failed.Attempts != 3 || failed.StatusCode != http.StatusBadGateway || failed.Error != "unexpected status code 502" ||
+		failed.Notification.OldState != "up" || failed.Notification.NewState != "down" || failed.Notification.LastError != "connection refused" ||
+		!failed.Notification.Timestamp.Equal(start) || failed.Webhook != "https://hooks.example.com" {
+		t.Errorf("unexpected delivery: %#v", failed)
+	}
+}
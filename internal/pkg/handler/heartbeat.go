@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+	"github.com/google/uuid"
+)
+
+// heartbeatRequest options of heartbeat check
+type heartbeatRequest struct {
+	// Grace optional duration (e.g. "1m") a ping may be late before the
+	// website goes down
+	Grace string `json:"grace,omitempty"`
+}
+
+type heartbeatResponse struct {
+	Token string `json:"token"`
+	// PingURL path of the URL the website must ping (POST)
+	PingURL  string     `json:"ping_url"`
+	Grace    string     `json:"grace,omitempty"`
+	LastPing *time.Time `json:"last_ping,omitempty"`
+}
+
+// newHeartbeat issues a unique ping token for a heartbeat website, nil
+// request results in no grace
+func newHeartbeat(request *heartbeatRequest) (storage.Heartbeat, error) {
+	var heartbeat storage.Heartbeat
+	if request != nil {
+		grace, err := parseOptionalDuration(request.Grace)
+		if err != nil {
+			return heartbeat, fmt.Errorf("invalid grace %q: %v", request.Grace, err)
+		}
+		heartbeat.Grace = grace
+	}
+	token, err := uuid.NewRandom()
+	if err != nil {
+		return heartbeat, fmt.Errorf("unable to generate token: %v", err)
+	}
+	heartbeat.Token = token.String()
+	heartbeat.Started = timeNowFunc()
+	return heartbeat, nil
+}
+
+// newHeartbeatResponse returns nil when the website is not a heartbeat so it
+// is omitted from the response
+func newHeartbeatResponse(heartbeat storage.Heartbeat) *heartbeatResponse {
+	if heartbeat.Token == "" {
+		return nil
+	}
+	response := &heartbeatResponse{
+		Token:   heartbeat.Token,
+		PingURL: "/ping/" + heartbeat.Token,
+	}
+	if heartbeat.Grace > 0 {
+		response.Grace = heartbeat.Grace.String()
+	}
+	if !heartbeat.LastPing.IsZero() {
+		lastPing := heartbeat.LastPing
+		response.LastPing = &lastPing
+	}
+	return response
+}
+
+// PingRecorder records pings of heartbeat websites. It is implemented by
+// updater.Updater so a ping is reported exactly like a successful check
+type PingRecorder interface {
+	RecordPing(ctx context.Context, websiteID string, at time.Time) error
+}
+
+// NewPingHandler initilize and get handler receiving pings of heartbeat
+// websites (POST). The ping token is taken from {token} path value
+func NewPingHandler(database storage.Database, recorder PingRecorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			ping(w, r, database, recorder)
+			return
+		}
+		log.Printf("%s - method %s is not allowed", r.URL.Path, r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// ping records a ping of the heartbeat website owning the token
+func ping(w http.ResponseWriter, r *http.Request, database storage.Database, recorder PingRecorder) {
+	website, err := database.GetByHeartbeatToken(r.PathValue("token"))
+	if err == storage.ErrNotFound {
+		http.Error(w, "heartbeat not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("unable to get website by heartbeat token from database: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	err = recorder.RecordPing(r.Context(), website.ID, timeNowFunc())
+	if err == storage.ErrNotFound {
+		http.Error(w, "heartbeat not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("unable to record ping of website with id: %s: %v", website.ID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("successfully record ping of website with id: %s", website.ID)
+	w.WriteHeader(http.StatusOK)
+}
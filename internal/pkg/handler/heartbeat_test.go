@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+	"github.com/ajiyakin/gohealthz/internal/pkg/updater"
+)
+
+func TestCreateHeartbeatAndPing(t *testing.T) {
+	// arrange
+	started := time.Now()
+	database := storage.NewInMemoryDatabase()
+	requestBody := createWebsiteRequest{
+		URL:       "nightly backup",
+		CheckType: "heartbeat",
+		Interval:  "24h",
+		Heartbeat: &heartbeatRequest{Grace: "1h"},
+	}
+	requestBodyRaw, err := json.Marshal(requestBody)
+	if err != nil {
+		t.Errorf("unable to marshal request body: %v", err)
+	}
+	request, err := http.NewRequest(http.MethodPost, "http://localhost:8080/website", bytes.NewReader(requestBodyRaw))
+	if err != nil {
+		t.Errorf("unable to create new HTTP request instance: %v", err)
+	}
+	responseRecorder := httptest.NewRecorder()
+	NewWebsiteHandler(database, time.Second)(responseRecorder, request)
+	if responseRecorder.Code != http.StatusCreated {
+		t.Fatalf("expected response code %d, got %d", http.StatusCreated, responseRecorder.Code)
+	}
+	websites, err := database.Get()
+	if err != nil || len(websites) != 1 {
+		t.Fatalf("expected 1 website, got %d: %v", len(websites), err)
+	}
+	created := websites[0]
+	if created.State != storage.StateUnknown || created.Heartbeat.Token == "" || created.Heartbeat.Grace != time.Hour {
+		t.Errorf("expected unknown heartbeat with token and grace, got %+v", created)
+	}
+	pingRequest, err := http.NewRequest(http.MethodPost, "http://localhost:8080/ping/"+created.Heartbeat.Token, nil)
+	if err != nil {
+		t.Errorf("unable to create new HTTP request instance: %v", err)
+	}
+	pingRequest.SetPathValue("token", created.Heartbeat.Token)
+	responseRecorder = httptest.NewRecorder()
+
+	// action
+	NewPingHandler(database, updater.New(database, updater.Config{}))(responseRecorder, pingRequest)
+
+	// acceptance
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("expected response code %d, got %d", http.StatusOK, responseRecorder.Code)
+	}
+	actual, err := database.GetByID(created.ID)
+	if err != nil {
+		t.Errorf("unable to get website: %v", err)
+	}
+	if actual.State != storage.StateUp || !actual.Healthy {
+		t.Errorf("expected pinged website to be up, got %s", actual.State)
+	}
+	if actual.Heartbeat.LastPing.Before(started) {
+		t.Errorf("expected last ping to be recorded, got %s", actual.Heartbeat.LastPing)
+	}
+	results, err := database.GetCheckResults(created.ID, time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	if len(results) != 1 || !results[0].Healthy {
+		t.Errorf("expected ping to be recorded as healthy check result, got %+v", results)
+	}
+}
+
+func TestCreateHeartbeatWithoutInterval(t *testing.T) {
+	// arrange
+	requestBodyRaw, err := json.Marshal(createWebsiteRequest{URL: "nightly backup", CheckType: "heartbeat"})
+	if err != nil {
+		t.Errorf("unable to marshal request body: %v", err)
+	}
+	request, err := http.NewRequest(http.MethodPost, "http://localhost:8080/website", bytes.NewReader(requestBodyRaw))
+	if err != nil {
+		t.Errorf("unable to create new HTTP request instance: %v", err)
+	}
+	responseRecorder := httptest.NewRecorder()
+
+	// action
+	NewWebsiteHandler(storage.NewInMemoryDatabase(), time.Second)(responseRecorder, request)
+
+	// acceptance
+	if responseRecorder.Code != http.StatusBadRequest {
+		t.Errorf("expected response code %d, got %d", http.StatusBadRequest, responseRecorder.Code)
+	}
+}
+
+func TestPingWithUnknownToken(t *testing.T) {
+	// arrange
+	database := storage.NewInMemoryDatabase()
+	err := database.Save(storage.Website{ID: "1234", URL: "https://example.com"})
+	if err != nil {
+		t.Errorf("unable to save to database: %v", err)
+	}
+	pingTests := []struct {
+		token        string
+		method       string
+		expectedCode int
+	}{
+		{"unknown", http.MethodPost, http.StatusNotFound},
+		{"", http.MethodPost, http.StatusNotFound},
+		{"unknown", http.MethodGet, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range pingTests {
+		t.Run(tt.method+" "+tt.token, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, "http://localhost:8080/ping/"+tt.token, nil)
+			if err != nil {
+				t.Errorf("unable to create new HTTP request instance: %v", err)
+			}
+			request.SetPathValue("token", tt.token)
+			responseRecorder := httptest.NewRecorder()
+
+			// action
+			NewPingHandler(database, updater.New(database, updater.Config{}))(responseRecorder, request)
+
+			// acceptance
+			if responseRecorder.Code != tt.expectedCode {
+				t.Errorf("expected response code %d, got %d", tt.expectedCode, responseRecorder.Code)
+			}
+		})
+	}
+}
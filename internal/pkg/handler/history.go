@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+type getCheckResultResponse struct {
+	Time       time.Time       `json:"time"`
+	Healthy    bool            `json:"healthy"`
+	State      string          `json:"state"`
+	StatusCode int             `json:"status_code"`
+	LatencyMS  int64           `json:"latency_ms"`
+	Timings    timingsResponse `json:"timings"`
+	Error      string          `json:"error,omitempty"`
+	// FailedAssertion the assertion that is not met by the response
+	FailedAssertion string `json:"failed_assertion,omitempty"`
+	// Certificate the certificate presented by the website, days to expiry
+	// are counted from time of the check
+	Certificate *certificateResponse `json:"certificate,omitempty"`
+	// Redirects URLs of followed redirects in order, the last one is the
+	// URL of the final response
+	Redirects []string `json:"redirects,omitempty"`
+	// Attempts number of attempts of the check including retries
+	Attempts int `json:"attempts,omitempty"`
+	// Maintenance whether the check happened within maintenance window
+	Maintenance bool `json:"maintenance,omitempty"`
+}
+
+type timingsResponse struct {
+	DNSMS          int64 `json:"dns_ms"`
+	ConnectMS      int64 `json:"connect_ms"`
+	TLSHandshakeMS int64 `json:"tls_handshake_ms"`
+	FirstByteMS    int64 `json:"first_byte_ms"`
+}
+
+// NewWebsiteHistoryHandler initilize and get handler for retrieving check
+// history of a website (GET). The website ID is taken from {id} path value,
+// and the history can be filtered with optional `from` and `to` query
+// parameters in RFC3339 format
+func NewWebsiteHistoryHandler(database storage.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			getWebsiteHistory(w, r, database)
+			return
+		}
+		log.Printf("%s - method %s is not allowed", r.URL.Path, r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func getWebsiteHistory(w http.ResponseWriter, r *http.Request, database storage.Database) {
+	websiteID := r.PathValue("id")
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		log.Printf("unable to parse time range: %v", err)
+		http.Error(w, "invalid time range. from and to must be in RFC3339 format", http.StatusBadRequest)
+		return
+	}
+	if _, err = database.GetByID(websiteID); err != nil {
+		if err == storage.ErrNotFound {
+			http.Error(w, "website not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("unable to get website with id: %s from database: %v", websiteID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	results, err := database.GetCheckResults(websiteID, from, to)
+	if err != nil {
+		log.Printf("unable to get check results of website with id: %s from database: %v", websiteID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	responseBody := make([]getCheckResultResponse, 0)
+	for _, result := range results {
+		responseBody = append(responseBody, newCheckResultResponse(result))
+	}
+	w.Header().Add("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(&responseBody); err != nil {
+		log.Printf("unable to encode check results to response writter: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("successfully retrieve check history of website with id: %s", websiteID)
+}
+
+// newCheckResultResponse converts check result into its response, days to
+// expiry of the certificate are counted from time of the check
+func newCheckResultResponse(result storage.CheckResult) getCheckResultResponse {
+	return getCheckResultResponse{
+		Time:       result.Time,
+		Healthy:    result.Healthy,
+		State:      string(result.State),
+		StatusCode: result.StatusCode,
+		LatencyMS:  result.Latency.Milliseconds(),
+		Timings: timingsResponse{
+			DNSMS:          result.Timings.DNS.Milliseconds(),
+			ConnectMS:      result.Timings.Connect.Milliseconds(),
+			TLSHandshakeMS: result.Timings.TLSHandshake.Milliseconds(),
+			FirstByteMS:    result.Timings.FirstByte.Milliseconds(),
+		},
+		Error:           result.Error,
+		FailedAssertion: result.FailedAssertion,
+		Certificate:     newCertificateResponse(result.Certificate, result.Time),
+		Redirects:       storage.RedactURLs(result.Redirects),
+		Attempts:        result.Attempts,
+		Maintenance:     result.Maintenance,
+	}
+}
+
+// parseTimeRange parses optional `from` and `to` query parameters. Missing
+// parameter results in zero time which means unbounded
+func parseTimeRange(r *http.Request) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return from, to, nil
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+func TestGetWebsiteHistoryWithTimeRange(t *testing.T) {
+	// arrange
+	database := storage.NewInMemoryDatabase()
+	if err := database.Save(storage.Website{ID: "1234", URL: "https://example.com"}); err != nil {
+		t.Errorf("unable to save to database: %v", err)
+	}
+	start := time.Date(2019, 3, 8, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		err := database.SaveCheckResult(storage.CheckResult{
+			WebsiteID:  "1234",
+			Time:       start.Add(time.Duration(i) * time.Hour),
+			Healthy:    true,
+			StatusCode: http.StatusOK,
+			Latency:    120 * time.Millisecond,
+		})
+		if err != nil {
+			t.Errorf("unable to save check result: %v", err)
+		}
+	}
+	request, err := http.NewRequest(http.MethodGet, "http://localhost:8080/website/1234/history?from=2019-03-08T11:00:00Z", nil)
+	if err != nil {
+		t.Errorf("unable to create new HTTP request instance: %v", err)
+	}
+	request.SetPathValue("id", "1234")
+	responseRecorder := httptest.NewRecorder()
+
+	// action
+	handlerFunc := NewWebsiteHistoryHandler(database)
+	handlerFunc(responseRecorder, request)
+
+	// acceptance
+	response := responseRecorder.Result()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("expected response code %d, got %d", http.StatusOK, response.StatusCode)
+	}
+	var responseBody []getCheckResultResponse
+	if err = json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
+		t.Errorf("unable to decode response body: %v", err)
+	}
+	if len(responseBody) != 2 {
+		t.Fatalf("expected 2 check results, got %#v", responseBody)
+	}
+	if responseBody[0].LatencyMS != 120 || responseBody[0].StatusCode != http.StatusOK {
+		t.Errorf("unexpected check result: %#v", responseBody[0])
+	}
+}
+
+func TestGetWebsiteHistoryWithInvalidTimeRange(t *testing.T) {
+	// arrange
+	database := storage.NewInMemoryDatabase()
+	request, err := http.NewRequest(http.MethodGet, "http://localhost:8080/website/1234/history?to=yesterday", nil)
+	if err != nil {
+		t.Errorf("unable to create new HTTP request instance: %v", err)
+	}
+	request.SetPathValue("id", "1234")
+	responseRecorder := httptest.NewRecorder()
+
+	// action
+	handlerFunc := NewWebsiteHistoryHandler(database)
+	handlerFunc(responseRecorder, request)
+
+	// acceptance
+	response := responseRecorder.Result()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected response code %d, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestGetWebsiteHistoryWithUnknownWebsite(t *testing.T) {
+	// arrange
+	database := storage.NewInMemoryDatabase()
+	request, err := http.NewRequest(http.MethodGet, "http://localhost:8080/website/1234/history", nil)
+	if err != nil {
+		t.Errorf("unable to create new HTTP request instance: %v", err)
+	}
+	request.SetPathValue("id", "1234")
+	responseRecorder := httptest.NewRecorder()
+
+	// action
+	handlerFunc := NewWebsiteHistoryHandler(database)
+	handlerFunc(responseRecorder, request)
+
+	// acceptance
+	response := responseRecorder.Result()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected response code %d, got %d", http.StatusNotFound, response.StatusCode)
+	}
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/maintenance"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+	"github.com/google/uuid"
+)
+
+type createMaintenanceRequest struct {
+	// WebsiteID the website the window applies to, either website_id or tag
+	// is required
+	WebsiteID string `json:"website_id,omitempty"`
+	// Tag the window applies to every website with this tag
+	Tag string `json:"tag,omitempty"`
+	// Start and End period of one-off window
+	Start time.Time `json:"start,omitempty"`
+	End   time.Time `json:"end,omitempty"`
+	// Schedule cron expression of every start of recurring window, each one
+	// lasting Duration (e.g. 2h)
+	Schedule    string `json:"schedule,omitempty"`
+	Duration    string `json:"duration,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type getMaintenanceResponse struct {
+	ID          string     `json:"id"`
+	WebsiteID   string     `json:"website_id,omitempty"`
+	Tag         string     `json:"tag,omitempty"`
+	Start       *time.Time `json:"start,omitempty"`
+	End         *time.Time `json:"end,omitempty"`
+	Schedule    string     `json:"schedule,omitempty"`
+	Duration    string     `json:"duration,omitempty"`
+	Description string     `json:"description,omitempty"`
+	// Active whether the window is in effect now
+	Active bool `json:"active"`
+}
+
+// NewMaintenanceHandler initilize and get handler for doing maintenance
+// window operations (POST, GET, DELETE)
+func NewMaintenanceHandler(database storage.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			createMaintenance(w, r, database)
+			return
+		}
+		if r.Method == http.MethodGet {
+			getMaintenance(w, r, database)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			deleteMaintenance(w, r, database)
+			return
+		}
+		log.Printf("%s - method %s is not allowed", r.URL.Path, r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func getMaintenance(w http.ResponseWriter, r *http.Request, database storage.Database) {
+	windows, err := database.GetMaintenanceWindows()
+	if err != nil {
+		log.Printf("unable to get list of maintenance window from database: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	responseBody := make([]getMaintenanceResponse, 0, len(windows))
+	now := timeNowFunc()
+	for _, window := range windows {
+		responseBody = append(responseBody, newMaintenanceResponse(window, now))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(responseBody); err != nil {
+		log.Printf("unable to encode response body: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+}
+
+func newMaintenanceResponse(window storage.MaintenanceWindow, now time.Time) getMaintenanceResponse {
+	response := getMaintenanceResponse{
+		ID:          window.ID,
+		WebsiteID:   window.WebsiteID,
+		Tag:         window.Tag,
+		Schedule:    window.Schedule,
+		Description: window.Description,
+		Active:      maintenance.Active(window, now),
+	}
+	if window.Schedule != "" {
+		response.Duration = window.Duration.String()
+		return response
+	}
+	start, end := window.Start, window.End
+	response.Start, response.End = &start, &end
+	return response
+}
+
+func createMaintenance(w http.ResponseWriter, r *http.Request, database storage.Database) {
+	var requestBody createMaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		log.Printf("unable to decode request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	var duration time.Duration
+	if requestBody.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(requestBody.Duration); err != nil {
+			log.Printf("unable to parse duration: %v with duration input: %s", err, requestBody.Duration)
+			http.Error(w, "invalid duration. duration must be a positive duration (e.g. 30m, 2h)", http.StatusBadRequest)
+			return
+		}
+	}
+	id, err := uuid.NewUUID()
+	if err != nil {
+		log.Printf("unable to generate new UUID: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	window := storage.MaintenanceWindow{
+		ID:          id.String(),
+		Description: requestBody.Description,
+		WebsiteID:   requestBody.WebsiteID,
+		Tag:         requestBody.Tag,
+		Start:       requestBody.Start,
+		End:         requestBody.End,
+		Schedule:    requestBody.Schedule,
+		Duration:    duration,
+	}
+	if err = maintenance.Validate(window); err != nil {
+		log.Printf("unable to validate maintenance window: %v", err)
+		http.Error(w, fmt.Sprintf("invalid maintenance window. %v", err), http.StatusBadRequest)
+		return
+	}
+	if window.WebsiteID != "" {
+		_, err = database.GetByID(window.WebsiteID)
+		if err == storage.ErrNotFound {
+			log.Printf("website with id: %s is not found", window.WebsiteID)
+			http.Error(w, "invalid website_id. website is not found", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("unable to get website with id: %s from database: %v", window.WebsiteID, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err = database.SaveMaintenanceWindow(window); err != nil {
+		log.Printf("unable to save maintenance window to database: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("successfully store maintenance window with id: %s to database", window.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(newMaintenanceResponse(window, timeNowFunc())); err != nil {
+		log.Printf("unable to encode response body: %v", err)
+	}
+}
+
+// deleteMaintenance removes a maintenance window from database. delete action
+// will ALWAYS return success whether the record is found or not within
+// database
+func deleteMaintenance(w http.ResponseWriter, r *http.Request, database storage.Database) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("unable to parse form: %v", err)
+		http.Error(w, "invalid form parameter", http.StatusBadRequest)
+		return
+	}
+	maintenanceID := r.FormValue("maintenance_id")
+	if maintenanceID == "" {
+		log.Printf("maintenance_id is empty")
+		http.Error(w, "maintenance_id is required", http.StatusBadRequest)
+		return
+	}
+	if err := database.DeleteMaintenanceWindow(maintenanceID); err != nil {
+		log.Printf("unable to delete a maintenance window with id: %s from database: %v", maintenanceID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("success delete maintenance window with id: %s", maintenanceID)
+	w.WriteHeader(http.StatusOK)
+}
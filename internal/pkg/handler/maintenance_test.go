@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+func TestCreateAndGetMaintenance(t *testing.T) {
+	// arrange
+	now := time.Date(2019, 3, 8, 12, 0, 0, 0, time.UTC)
+	timeNowFunc = func() time.Time { return now }
+	defer func() { timeNowFunc = time.Now }()
+	database := storage.NewInMemoryDatabase()
+	if err := database.Save(storage.Website{ID: "1234", URL: "https://example.com", Tags: []string{"eu"}}); err != nil {
+		t.Fatalf("unable to save to database: %v", err)
+	}
+	requests := []createMaintenanceRequest{
+		{WebsiteID: "1234", Start: now.Add(-time.Hour), End: now.Add(time.Hour), Description: "database upgrade"},
+		{Tag: "eu", Schedule: "0 3 * * *", Duration: "2h"},
+	}
+	handlerFunc := NewMaintenanceHandler(database)
+
+	// action
+	for _, requestBody := range requests {
+		requestBodyRaw, err := json.Marshal(requestBody)
+		if err != nil {
+			t.Fatalf("unable to marshal request body: %v", err)
+		}
+		request := httptest.NewRequest(http.MethodPost, "http://localhost:8080/maintenance", bytes.NewReader(requestBodyRaw))
+		responseRecorder := httptest.NewRecorder()
+		handlerFunc(responseRecorder, request)
+		if responseRecorder.Code != http.StatusCreated {
+			t.Fatalf("expected response code %d, got %d: %s", http.StatusCreated, responseRecorder.Code, responseRecorder.Body)
+		}
+	}
+	request := httptest.NewRequest(http.MethodGet, "http://localhost:8080/maintenance", nil)
+	responseRecorder := httptest.NewRecorder()
+	handlerFunc(responseRecorder, request)
+
+	// acceptance
+	var responseBody []getMaintenanceResponse
+	if err := json.NewDecoder(responseRecorder.Body).Decode(&responseBody); err != nil {
+		t.Fatalf("unable to decode response body: %v", err)
+	}
+	if len(responseBody) != 2 {
+		t.Fatalf("expected 2 maintenance windows, got %d", len(responseBody))
+	}
+	active := 0
+	for _, window := range responseBody {
+		if window.Tag == "eu" {
+			if window.Schedule != "0 3 * * *" || window.Duration != "2h0m0s" || window.Start != nil || window.Active {
+				t.Errorf("unexpected recurring window: %+v", window)
+			}
+			continue
+		}
+		if window.WebsiteID != "1234" || window.Start == nil || !window.Start.Equal(now.Add(-time.Hour)) ||
+			window.Description != "database upgrade" || !window.Active {
+			t.Errorf("unexpected one-off window: %+v", window)
+		}
+		active++
+	}
+	if active != 1 {
+		t.Errorf("expected 1 active window, got %d", active)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "http://localhost:8080/website", nil)
+	responseRecorder = httptest.NewRecorder()
+	NewWebsiteHandler(database, time.Second)(responseRecorder, request)
+	var websites []getWebsitesResponse
+	if err := json.NewDecoder(responseRecorder.Body).Decode(&websites); err != nil {
+		t.Fatalf("unable to decode response body: %v", err)
+	}
+	if len(websites) != 1 || !websites[0].Maintenance {
+		t.Errorf("expected website to be under maintenance, got %+v", websites)
+	}
+}
+
+func TestCreateMaintenanceWithInvalidWindow(t *testing.T) {
+	// arrange
+	start := time.Date(2019, 3, 8, 12, 0, 0, 0, time.UTC)
+	database := storage.NewInMemoryDatabase()
+	if err := database.Save(storage.Website{ID: "1234", URL: "https://example.com"}); err != nil {
+		t.Fatalf("unable to save to database: %v", err)
+	}
+	invalidTests := []struct {
+		name        string
+		requestBody createMaintenanceRequest
+	}{
+		{"no target", createMaintenanceRequest{Start: start, End: start.Add(time.Hour)}},
+		{"website and tag", createMaintenanceRequest{WebsiteID: "1234", Tag: "eu", Start: start, End: start.Add(time.Hour)}},
+		{"unknown website", createMaintenanceRequest{WebsiteID: "unknown", Start: start, End: start.Add(time.Hour)}},
+		{"end before start", createMaintenanceRequest{WebsiteID: "1234", Start: start, End: start.Add(-time.Hour)}},
+		{"one-off and recurring", createMaintenanceRequest{WebsiteID: "1234", Start: start, End: start.Add(time.Hour), Schedule: "@daily", Duration: "1h"}},
+		{"invalid schedule", createMaintenanceRequest{Tag: "eu", Schedule: "61 * * * *", Duration: "1h"}},
+		{"invalid duration", createMaintenanceRequest{Tag: "eu", Schedule: "@daily", Duration: "soon"}},
+		{"missing duration", createMaintenanceRequest{Tag: "eu", Schedule: "@daily"}},
+	}
+
+	for _, tt := range invalidTests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestBodyRaw, err := json.Marshal(tt.requestBody)
+			if err != nil {
+				t.Fatalf("unable to marshal request body: %v", err)
+			}
+			request := httptest.NewRequest(http.MethodPost, "http://localhost:8080/maintenance", bytes.NewReader(requestBodyRaw))
+			responseRecorder := httptest.NewRecorder()
+
+			// action
+			handlerFunc := NewMaintenanceHandler(database)
+			handlerFunc(responseRecorder, request)
+
+			// acceptance
+			if responseRecorder.Code != http.StatusBadRequest {
+				t.Errorf("expected response code %d, got %d", http.StatusBadRequest, responseRecorder.Code)
+			}
+			windows, err := database.GetMaintenanceWindows()
+			if err != nil {
+				t.Fatalf("unable to get maintenance windows: %v", err)
+			}
+			if len(windows) != 0 {
+				t.Errorf("expected no maintenance window to be stored, got %d", len(windows))
+			}
+		})
+	}
+}
+
+func TestDeleteMaintenance(t *testing.T) {
+	// arrange
+	database := storage.NewInMemoryDatabase()
+	err := database.SaveMaintenanceWindow(storage.MaintenanceWindow{ID: "5678", Tag: "eu", Schedule: "@daily", Duration: time.Hour})
+	if err != nil {
+		t.Fatalf("unable to save maintenance window to database: %v", err)
+	}
+	formValues := url.Values{
+		"maintenance_id": []string{"5678"},
+	}
+	request := httptest.NewRequest(http.MethodDelete, "http://localhost:8080/maintenance?"+formValues.Encode(), nil)
+	responseRecorder := httptest.NewRecorder()
+
+	// action
+	handlerFunc := NewMaintenanceHandler(database)
+	handlerFunc(responseRecorder, request)
+
+	// acceptance
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("expected response code %d, got %d", http.StatusOK, responseRecorder.Code)
+	}
+	windows, err := database.GetMaintenanceWindows()
+	if err != nil {
+		t.Fatalf("unable to get maintenance windows: %v", err)
+	}
+	if len(windows) != 0 {
+		t.Errorf("expected maintenance window to be deleted, got %d", len(windows))
+	}
+}
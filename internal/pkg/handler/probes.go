@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/checker"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// tcpRequest options of TCP check, both are optional
+type tcpRequest struct {
+	// Send bytes written once the connection is established
+	Send string `json:"send,omitempty"`
+	// Expect bytes that must be received (e.g. "220" of SMTP banner)
+	Expect string `json:"expect,omitempty"`
+}
+
+// parseTCPOptions converts TCP options of request into storage model, nil
+// request results in no options
+func parseTCPOptions(request *tcpRequest) storage.TCPOptions {
+	if request == nil {
+		return storage.TCPOptions{}
+	}
+	return storage.TCPOptions{Send: request.Send, Expect: request.Expect}
+}
+
+// newTCPResponse returns nil when there are no TCP options so they are
+// omitted from the response
+func newTCPResponse(options storage.TCPOptions) *tcpRequest {
+	if options == (storage.TCPOptions{}) {
+		return nil
+	}
+	return &tcpRequest{Send: options.Send, Expect: options.Expect}
+}
+
+// dnsRequest options of DNS check, every one of them is optional
+type dnsRequest struct {
+	// RecordType type of resolved records: A (default), AAAA, CNAME, TXT or
+	// MX
+	RecordType string `json:"record_type,omitempty"`
+	// Resolver address (host:port) of DNS server, resolver of the system is
+	// used when it is empty
+	Resolver string `json:"resolver,omitempty"`
+	// Expected values that must be resolved
+	Expected []string `json:"expected,omitempty"`
+}
+
+// parseDNSOptions converts DNS options of request into storage model, nil
+// request results in no options
+func parseDNSOptions(request *dnsRequest) storage.DNSOptions {
+	if request == nil {
+		return storage.DNSOptions{}
+	}
+	return storage.DNSOptions{
+		RecordType: request.RecordType,
+		Resolver:   request.Resolver,
+		Expected:   request.Expected,
+	}
+}
+
+// newDNSResponse returns nil when there are no DNS options so they are
+// omitted from the response
+func newDNSResponse(options storage.DNSOptions) *dnsRequest {
+	if options.RecordType == "" && options.Resolver == "" && len(options.Expected) == 0 {
+		return nil
+	}
+	return &dnsRequest{
+		RecordType: options.RecordType,
+		Resolver:   options.Resolver,
+		Expected:   options.Expected,
+	}
+}
+
+// grpcRequest options of gRPC check
+type grpcRequest struct {
+	// Service name of the checked service, health of the whole server is
+	// checked when it is empty
+	Service string `json:"service,omitempty"`
+}
+
+// parseGRPCOptions converts gRPC options of request into storage model, nil
+// request results in no options
+func parseGRPCOptions(request *grpcRequest) storage.GRPCOptions {
+	if request == nil {
+		return storage.GRPCOptions{}
+	}
+	return storage.GRPCOptions{Service: request.Service}
+}
+
+// newGRPCResponse returns nil when there are no gRPC options so they are
+// omitted from the response
+func newGRPCResponse(options storage.GRPCOptions) *grpcRequest {
+	if options == (storage.GRPCOptions{}) {
+		return nil
+	}
+	return &grpcRequest{Service: options.Service}
+}
+
+// webSocketRequest options of WebSocket check, both are optional
+type webSocketRequest struct {
+	// Send text message sent once the handshake is done
+	Send string `json:"send,omitempty"`
+	// Expect substring the reply must contain
+	Expect string `json:"expect,omitempty"`
+}
+
+// parseWebSocketOptions converts WebSocket options of request into storage
+// model, nil request results in no options
+func parseWebSocketOptions(request *webSocketRequest) storage.WebSocketOptions {
+	if request == nil {
+		return storage.WebSocketOptions{}
+	}
+	return storage.WebSocketOptions{Send: request.Send, Expect: request.Expect}
+}
+
+// newWebSocketResponse returns nil when there are no WebSocket options so
+// they are omitted from the response
+func newWebSocketResponse(options storage.WebSocketOptions) *webSocketRequest {
+	if options == (storage.WebSocketOptions{}) {
+		return nil
+	}
+	return &webSocketRequest{Send: options.Send, Expect: options.Expect}
+}
+
+// certificateResponse TLS certificate presented by a website
+type certificateResponse struct {
+	NotAfter   time.Time `json:"not_after"`
+	Issuer     string    `json:"issuer"`
+	DNSNames   []string  `json:"dns_names"`
+	ChainValid bool      `json:"chain_valid"`
+	// DaysToExpiry number of whole days until the certificate expires,
+	// negative when it is already expired
+	DaysToExpiry int `json:"days_to_expiry"`
+}
+
+// newCertificateResponse returns nil when there is no certificate so it is
+// omitted from the response, days to expiry are counted from now
+func newCertificateResponse(certificate *storage.Certificate, now time.Time) *certificateResponse {
+	if certificate == nil {
+		return nil
+	}
+	dnsNames := certificate.DNSNames
+	if dnsNames == nil {
+		dnsNames = make([]string, 0)
+	}
+	return &certificateResponse{
+		NotAfter:     certificate.NotAfter,
+		Issuer:       certificate.Issuer,
+		DNSNames:     dnsNames,
+		ChainValid:   certificate.ChainValid,
+		DaysToExpiry: checker.DaysToExpiry(*certificate, now),
+	}
+}
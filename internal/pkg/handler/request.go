@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// redacted replaces secrets of website request in responses
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders headers whose values are always redacted, along with
+// every header whose name mentions one of sensitiveHeaderWords
+var (
+	sensitiveHeaders     = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+	sensitiveHeaderWords = []string{"token", "secret", "key", "password", "session"}
+)
+
+// httpRequest options of the request sent by HTTP check, every one of them
+// is optional
+type httpRequest struct {
+	// Method HTTP method (e.g. "POST"), GET is used when it is empty
+	Method string `json:"method,omitempty"`
+	// Headers additional headers by their name, Host header overrides host
+	// of the request
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Auth    *authRequest      `json:"auth,omitempty"`
+}
+
+// authRequest credentials of the request, either username and password of
+// basic authentication or bearer token
+type authRequest struct {
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	BearerToken string `json:"bearer_token,omitempty"`
+}
+
+// parseHTTPRequest converts request options of request into storage model,
+// nil request results in GET request without body
+func parseHTTPRequest(request *httpRequest) storage.HTTPRequest {
+	if request == nil {
+		return storage.HTTPRequest{}
+	}
+	options := storage.HTTPRequest{
+		Method:  request.Method,
+		Headers: request.Headers,
+		Body:    request.Body,
+	}
+	if request.Auth != nil {
+		options.Auth = storage.Auth{
+			Username:    request.Auth.Username,
+			Password:    request.Auth.Password,
+			BearerToken: request.Auth.BearerToken,
+		}
+	}
+	return options
+}
+
+// newHTTPRequestResponse returns nil when the default request is used so it
+// is omitted from the response. Body, password, bearer token and values of
+// sensitive headers are redacted
+func newHTTPRequestResponse(options storage.HTTPRequest) *httpRequest {
+	if options.Method == "" && len(options.Headers) == 0 && options.Body == "" && options.Auth == (storage.Auth{}) {
+		return nil
+	}
+	response := &httpRequest{Method: options.Method}
+	if options.Body != "" {
+		response.Body = redacted
+	}
+	if len(options.Headers) > 0 {
+		response.Headers = make(map[string]string, len(options.Headers))
+		for name, value := range options.Headers {
+			if isSensitiveHeader(name) {
+				value = redacted
+			}
+			response.Headers[name] = value
+		}
+	}
+	if options.Auth != (storage.Auth{}) {
+		response.Auth = &authRequest{Username: options.Auth.Username}
+		if options.Auth.Password != "" {
+			response.Auth.Password = redacted
+		}
+		if options.Auth.BearerToken != "" {
+			response.Auth.BearerToken = redacted
+		}
+	}
+	return response
+}
+
+// redirectsRequest redirect policy of HTTP check, every one of them is
+// optional
+type redirectsRequest struct {
+	// NoFollow the redirect response is evaluated instead of being followed
+	NoFollow bool `json:"no_follow,omitempty"`
+	// MaxHops maximum number of followed redirects, 10 is used when it is
+	// zero
+	MaxHops int `json:"max_hops,omitempty"`
+	// FinalURL URL the final response must come from
+	FinalURL string `json:"final_url,omitempty"`
+}
+
+// parseRedirectPolicy converts redirect policy of request into storage model,
+// nil request results in the default policy
+func parseRedirectPolicy(request *redirectsRequest) storage.RedirectPolicy {
+	if request == nil {
+		return storage.RedirectPolicy{}
+	}
+	return storage.RedirectPolicy{
+		NoFollow: request.NoFollow,
+		MaxHops:  request.MaxHops,
+		FinalURL: request.FinalURL,
+	}
+}
+
+// newRedirectsResponse returns nil when the default policy is used so it is
+// omitted from the response
+func newRedirectsResponse(policy storage.RedirectPolicy) *redirectsRequest {
+	if policy == (storage.RedirectPolicy{}) {
+		return nil
+	}
+	return &redirectsRequest{
+		NoFollow: policy.NoFollow,
+		MaxHops:  policy.MaxHops,
+		FinalURL: storage.RedactURL(policy.FinalURL),
+	}
+}
+
+// isSensitiveHeader reports whether value of the header may hold a secret
+func isSensitiveHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	for _, sensitive := range sensitiveHeaders {
+		if name == sensitive {
+			return true
+		}
+	}
+	name = strings.ToLower(name)
+	for _, word := range sensitiveHeaderWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/checker"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+func TestCreateWebsiteWithRequest(t *testing.T) {
+	// arrange
+	var received *http.Request
+	var receivedBody []byte
+	checkers = checker.NewRegistry(&http.Client{Transport: roundTripFunc(func(request *http.Request) (*http.Response, error) {
+		received = request
+		receivedBody, _ = ioutil.ReadAll(request.Body)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: request}, nil
+	})})
+	requestBody := createWebsiteRequest{
+		URL: "https://api.example.com/health",
+		Request: &httpRequest{
+			Method:  http.MethodPost,
+			Headers: map[string]string{"X-Api-Key": "k3y", "Accept": "application/json"},
+			Body:    `{"deep":true}`,
+			Auth:    &authRequest{BearerToken: "t0ken"},
+		},
+	}
+	requestBodyRaw, err := json.Marshal(requestBody)
+	if err != nil {
+		t.Errorf("unable to marshal request body: %v", err)
+	}
+	request, err := http.NewRequest(http.MethodPost, "http://localhost:8080/website", bytes.NewReader(requestBodyRaw))
+	if err != nil {
+		t.Errorf("unable to create new HTTP request instance: %v", err)
+	}
+	database := storage.NewInMemoryDatabase()
+	handlerFunc := NewWebsiteHandler(database, time.Second)
+
+	// action
+	createRecorder := httptest.NewRecorder()
+	handlerFunc(createRecorder, request)
+	getRecorder := httptest.NewRecorder()
+	handlerFunc(getRecorder, httptest.NewRequest(http.MethodGet, "http://localhost:8080/website", nil))
+
+	// acceptance
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("expected response code %d, got %d", http.StatusCreated, createRecorder.Code)
+	}
+	if received.Method != http.MethodPost || string(receivedBody) != `{"deep":true}` {
+		t.Errorf("expected POST request with body, got %s request with body %q", received.Method, receivedBody)
+	}
+	if received.Header.Get("Authorization") != "Bearer t0ken" || received.Header.Get("X-Api-Key") != "k3y" {
+		t.Errorf("expected credentials to be sent, got headers %v", received.Header)
+	}
+	var responseBody []getWebsitesResponse
+	if err = json.NewDecoder(getRecorder.Body).Decode(&responseBody); err != nil {
+		t.Errorf("unable to decode response body: %v", err)
+	}
+	if len(responseBody) != 1 {
+		t.Fatalf("expected 1 website, got %d", len(responseBody))
+	}
+	expected := &httpRequest{
+		Method:  http.MethodPost,
+		Headers: map[string]string{"X-Api-Key": redacted, "Accept": "application/json"},
+		Body:    redacted,
+		Auth:    &authRequest{BearerToken: redacted},
+	}
+	if !reflect.DeepEqual(responseBody[0].Request, expected) {
+		t.Errorf("expected request %+v, got %+v", expected, responseBody[0].Request)
+	}
+}
+
+func TestCreateWebsiteWithInvalidRequest(t *testing.T) {
+	// arrange
+	mockStatusCode(http.StatusOK)
+	requestBody := createWebsiteRequest{
+		URL:     "https://api.example.com/health",
+		Request: &httpRequest{Auth: &authRequest{Username: "admin", Password: "secret", BearerToken: "t0ken"}},
+	}
+	requestBodyRaw, err := json.Marshal(requestBody)
+	if err != nil {
+		t.Errorf("unable to marshal request body: %v", err)
+	}
+	request, err := http.NewRequest(http.MethodPost, "http://localhost:8080/website", bytes.NewReader(requestBodyRaw))
+	if err != nil {
+		t.Errorf("unable to create new HTTP request instance: %v", err)
+	}
+	responseRecorder := httptest.NewRecorder()
+	database := storage.NewInMemoryDatabase()
+
+	// action
+	handlerFunc := NewWebsiteHandler(database, time.Second)
+	handlerFunc(responseRecorder, request)
+
+	// acceptance
+	if responseRecorder.Code != http.StatusBadRequest {
+		t.Errorf("expected response code %d, got %d", http.StatusBadRequest, responseRecorder.Code)
+	}
+	websites, _ := database.Get()
+	if len(websites) != 0 {
+		t.Errorf("expected no website to be stored, got %d", len(websites))
+	}
+}
+
+func TestCreateWebsiteWithRedirects(t *testing.T) {
+	// arrange
+	mockStatusCode(http.StatusOK)
+	redirectTests := []struct {
+		testName     string
+		redirects    *redirectsRequest
+		expectedCode int
+	}{
+		{"no follow", &redirectsRequest{NoFollow: true}, http.StatusCreated},
+		{"max hops and final URL", &redirectsRequest{MaxHops: 3, FinalURL: "https://www.example.com/home"}, http.StatusCreated},
+		{"negative max hops", &redirectsRequest{MaxHops: -1}, http.StatusBadRequest},
+		{"relative final URL", &redirectsRequest{FinalURL: "home"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range redirectTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			requestBody := createWebsiteRequest{URL: "https://www.example.com", Redirects: tt.redirects}
+			requestBodyRaw, err := json.Marshal(requestBody)
+			if err != nil {
+				t.Errorf("unable to marshal request body: %v", err)
+			}
+			request, err := http.NewRequest(http.MethodPost, "http://localhost:8080/website", bytes.NewReader(requestBodyRaw))
+			if err != nil {
+				t.Errorf("unable to create new HTTP request instance: %v", err)
+			}
+			responseRecorder := httptest.NewRecorder()
+			database := storage.NewInMemoryDatabase()
+
+			// action
+			handlerFunc := NewWebsiteHandler(database, time.Second)
+			handlerFunc(responseRecorder, request)
+
+			// acceptance
+			if responseRecorder.Code != tt.expectedCode {
+				t.Fatalf("expected response code %d, got %d", tt.expectedCode, responseRecorder.Code)
+			}
+			websites, _ := database.Get()
+			if tt.expectedCode != http.StatusCreated {
+				return
+			}
+			if len(websites) != 1 || newRedirectsResponse(websites[0].Redirects) == nil || *newRedirectsResponse(websites[0].Redirects) != *tt.redirects {
+				t.Errorf("expected redirect policy %+v to be stored, got %+v", *tt.redirects, websites)
+			}
+		})
+	}
+}
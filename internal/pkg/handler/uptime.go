@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+	"github.com/ajiyakin/gohealthz/internal/pkg/uptime"
+)
+
+var (
+	timeNowFunc = time.Now
+
+	// uptimeWindows rolling windows reported by uptime handler
+	uptimeWindows = []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"24h", 24 * time.Hour},
+		{"7d", 7 * 24 * time.Hour},
+		{"30d", 30 * 24 * time.Hour},
+	}
+)
+
+type getUptimeResponse struct {
+	WebsiteID string                          `json:"website_id"`
+	Windows   map[string]uptimeWindowResponse `json:"windows"`
+}
+
+type uptimeWindowResponse struct {
+	AvailabilityPercent float64 `json:"availability_percent"`
+	MonitoredSeconds    float64 `json:"monitored_seconds"`
+	// Complete whether check history covers the whole window, statistics of
+	// incomplete window only cover its monitored part
+	Complete        bool    `json:"complete"`
+	DowntimeSeconds float64 `json:"downtime_seconds"`
+	Incidents       int     `json:"incidents"`
+	MTTRSeconds     float64 `json:"mttr_seconds"`
+	MTBFSeconds     float64 `json:"mtbf_seconds"`
+}
+
+// NewWebsiteUptimeHandler initilize and get handler for retrieving uptime
+// (availability, downtime, MTTR and MTBF) of a website over rolling windows
+// of 24 hours, 7 days and 30 days (GET). The website ID is taken from {id}
+// path value
+func NewWebsiteUptimeHandler(database storage.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			getWebsiteUptime(w, r, database)
+			return
+		}
+		log.Printf("%s - method %s is not allowed", r.URL.Path, r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func getWebsiteUptime(w http.ResponseWriter, r *http.Request, database storage.Database) {
+	websiteID := r.PathValue("id")
+	if _, err := database.GetByID(websiteID); err != nil {
+		if err == storage.ErrNotFound {
+			http.Error(w, "website not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("unable to get website with id: %s from database: %v", websiteID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	// the whole history is retrieved because the result preceding a window
+	// tells the state of the website at the beginning of the window
+	results, err := database.GetCheckResults(websiteID, time.Time{}, time.Time{})
+	if err != nil {
+		log.Printf("unable to get check results of website with id: %s from database: %v", websiteID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	now := timeNowFunc()
+	responseBody := getUptimeResponse{
+		WebsiteID: websiteID,
+		Windows:   make(map[string]uptimeWindowResponse, len(uptimeWindows)),
+	}
+	for _, window := range uptimeWindows {
+		report := uptime.Compute(results, now.Add(-window.duration), now)
+		responseBody.Windows[window.name] = uptimeWindowResponse{
+			AvailabilityPercent: report.Availability,
+			MonitoredSeconds:    report.Monitored.Seconds(),
+			Complete:            report.Complete,
+			DowntimeSeconds:     report.Downtime.Seconds(),
+			Incidents:           report.Incidents,
+			MTTRSeconds:         report.MTTR.Seconds(),
+			MTBFSeconds:         report.MTBF.Seconds(),
+		}
+	}
+	w.Header().Add("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(&responseBody); err != nil {
+		log.Printf("unable to encode uptime to response writter: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("successfully retrieve uptime of website with id: %s", websiteID)
+}
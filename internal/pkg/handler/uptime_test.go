@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+func TestGetWebsiteUptime(t *testing.T) {
+	// arrange
+	now := time.Date(2019, 3, 8, 12, 0, 0, 0, time.UTC)
+	timeNowFunc = func() time.Time { return now }
+	defer func() { timeNowFunc = time.Now }()
+	database := storage.NewInMemoryDatabase()
+	if err := database.Save(storage.Website{ID: "1234", URL: "https://example.com"}); err != nil {
+		t.Errorf("unable to save to database: %v", err)
+	}
+	results := []storage.CheckResult{
+		// down for 1 hour 2 days ago, only counted by 7d and 30d windows
+		{WebsiteID: "1234", Time: now.Add(-48 * time.Hour), Healthy: false},
+		{WebsiteID: "1234", Time: now.Add(-47 * time.Hour), Healthy: true},
+		// down for 6 hours within the last day
+		{WebsiteID: "1234", Time: now.Add(-12 * time.Hour), Healthy: false},
+		{WebsiteID: "1234", Time: now.Add(-6 * time.Hour), Healthy: true},
+	}
+	for _, result := range results {
+		if err := database.SaveCheckResult(result); err != nil {
+			t.Errorf("unable to save check result: %v", err)
+		}
+	}
+	request, err := http.NewRequest(http.MethodGet, "http://localhost:8080/website/1234/uptime", nil)
+	if err != nil {
+		t.Errorf("unable to create new HTTP request instance: %v", err)
+	}
+	request.SetPathValue("id", "1234")
+	responseRecorder := httptest.NewRecorder()
+
+	// action
+	handlerFunc := NewWebsiteUptimeHandler(database)
+	handlerFunc(responseRecorder, request)
+
+	// acceptance
+	response := responseRecorder.Result()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("expected response code %d, got %d", http.StatusOK, response.StatusCode)
+	}
+	var responseBody getUptimeResponse
+	if err = json.NewDecoder(response.Body).Decode(&responseBody); err != nil {
+		t.Errorf("unable to decode response body: %v", err)
+	}
+	day := responseBody.Windows["24h"]
+	if day.AvailabilityPercent != 75 || day.DowntimeSeconds != 6*3600 || day.Incidents != 1 || !day.Complete {
+		t.Errorf("unexpected 24h uptime: %#v", day)
+	}
+	week := responseBody.Windows["7d"]
+	// history of 2 days does not cover the whole week
+	if week.DowntimeSeconds != 7*3600 || week.Incidents != 2 || week.MTTRSeconds != 3.5*3600 || week.Complete {
+		t.Errorf("unexpected 7d uptime: %#v", week)
+	}
+	if _, ok := responseBody.Windows["30d"]; !ok {
+		t.Errorf("expected 30d window to be reported, got %#v", responseBody.Windows)
+	}
+}
+
+func TestGetWebsiteUptimeWithUnknownWebsite(t *testing.T) {
+	// arrange
+	database := storage.NewInMemoryDatabase()
+	request, err := http.NewRequest(http.MethodGet, "http://localhost:8080/website/1234/uptime", nil)
+	if err != nil {
+		t.Errorf("unable to create new HTTP request instance: %v", err)
+	}
+	request.SetPathValue("id", "1234")
+	responseRecorder := httptest.NewRecorder()
+
+	// action
+	handlerFunc := NewWebsiteUptimeHandler(database)
+	handlerFunc(responseRecorder, request)
+
+	// acceptance
+	response := responseRecorder.Result()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected response code %d, got %d", http.StatusNotFound, response.StatusCode)
+	}
+}
@@ -1,35 +1,110 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
-	"net/url"
+	"time"
 
+	"github.com/ajiyakin/gohealthz/internal/pkg/checker"
+	"github.com/ajiyakin/gohealthz/internal/pkg/cron"
+	"github.com/ajiyakin/gohealthz/internal/pkg/maintenance"
+	"github.com/ajiyakin/gohealthz/internal/pkg/state"
 	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
 	"github.com/google/uuid"
 )
 
 var (
-	httpGetRequestFunc = http.Get
+	// checkers probes used for the initial check of a created website.
+	// Timeout is applied per check since every website may have its own
+	// timeout
+	checkers = checker.NewRegistry(&http.Client{})
 )
 
 type createWebsiteRequest struct {
 	URL string `json:"url"`
+	// Tags optional labels of the website, e.g. to apply maintenance windows
+	// to a group of websites
+	Tags []string `json:"tags,omitempty"`
+	// CheckType optional type of check (e.g. "http"), HTTP is used when it
+	// is empty
+	CheckType string `json:"check_type,omitempty"`
+	// Interval and Timeout are optional durations (e.g. "30s", "5m"),
+	// defaults of the updater are used when they are empty
+	Interval string `json:"interval,omitempty"`
+	Timeout  string `json:"timeout,omitempty"`
+	// Schedule optional cron expression (e.g. "*/15 9-17 * * mon-fri") of
+	// when the website is checked, as an alternative to interval
+	Schedule string `json:"schedule,omitempty"`
+	// DegradedLatency optional duration, website responding slower than it
+	// is considered degraded
+	DegradedLatency string `json:"degraded_latency,omitempty"`
+	// Request optional method, headers, body and credentials of the request
+	// sent by HTTP check
+	Request *httpRequest `json:"request,omitempty"`
+	// Redirects optional redirect policy of HTTP check
+	Redirects  *redirectsRequest  `json:"redirects,omitempty"`
+	Assertions *assertionsRequest `json:"assertions,omitempty"`
+	// TCP optional options of TCP check
+	TCP *tcpRequest `json:"tcp,omitempty"`
+	// DNS optional options of DNS check
+	DNS *dnsRequest `json:"dns,omitempty"`
+	// GRPC optional options of gRPC check
+	GRPC *grpcRequest `json:"grpc,omitempty"`
+	// WebSocket optional options of WebSocket check
+	WebSocket *webSocketRequest `json:"websocket,omitempty"`
+	// Heartbeat optional options of heartbeat check, interval is the
+	// expected period between two pings
+	Heartbeat *heartbeatRequest `json:"heartbeat,omitempty"`
+	// CertificateExpiryDays optional number of days before its certificate
+	// expires the website is considered degraded
+	CertificateExpiryDays int `json:"certificate_expiry_days,omitempty"`
+	// FailureThreshold and SuccessThreshold are optional number of
+	// consecutive check results before the state flips
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+	SuccessThreshold int `json:"success_threshold,omitempty"`
 }
 
 type getWebsitesResponse struct {
-	ID     string `json:"id"`
-	URL    string `json:"url"`
-	Healty bool   `json:"healty"`
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	CheckType string   `json:"check_type"`
+	Tags      []string `json:"tags,omitempty"`
+	Healty    bool     `json:"healty"`
+	State     string   `json:"state"`
+	// Maintenance whether the website is within maintenance window now
+	Maintenance     bool               `json:"maintenance"`
+	Interval        string             `json:"interval,omitempty"`
+	Timeout         string             `json:"timeout,omitempty"`
+	Schedule        string             `json:"schedule,omitempty"`
+	DegradedLatency string             `json:"degraded_latency,omitempty"`
+	Request         *httpRequest       `json:"request,omitempty"`
+	Redirects       *redirectsRequest  `json:"redirects,omitempty"`
+	Assertions      *assertionsRequest `json:"assertions,omitempty"`
+	TCP             *tcpRequest        `json:"tcp,omitempty"`
+	DNS             *dnsRequest        `json:"dns,omitempty"`
+	GRPC            *grpcRequest       `json:"grpc,omitempty"`
+	WebSocket       *webSocketRequest  `json:"websocket,omitempty"`
+	Heartbeat       *heartbeatResponse `json:"heartbeat,omitempty"`
+	// CertificateExpiryDays is omitted when the default number of days is
+	// used, and Certificate when the website does not use TLS
+	CertificateExpiryDays int                  `json:"certificate_expiry_days,omitempty"`
+	Certificate           *certificateResponse `json:"certificate,omitempty"`
+	// FailureThreshold and SuccessThreshold are omitted when the default
+	// thresholds are used
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+	SuccessThreshold int `json:"success_threshold,omitempty"`
 }
 
 // NewWebsiteHandler initilize and get handler for doing website operations
-// (POST, GET, DELETE)
-func NewWebsiteHandler(database storage.Database) http.HandlerFunc {
+// (POST, GET, DELETE). Timeout is the default timeout of the initial check of
+// a created website, used when the website has no timeout of its own
+func NewWebsiteHandler(database storage.Database, timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			createWebsite(w, r, database)
+			createWebsite(w, r, database, timeout)
 			return
 		}
 		if r.Method == http.MethodGet {
@@ -52,15 +127,52 @@ func getWebsites(w http.ResponseWriter, r *http.Request, database storage.Databa
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
+	windows, err := database.GetMaintenanceWindows()
+	if err != nil {
+		log.Printf("unable to get list of maintenance window from database: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
 	// initilize with make with 0 capacity so if there's no records found,
 	// the response body will be [] instead of null
 	responseBody := make([]getWebsitesResponse, 0)
+	now := timeNowFunc()
 	for _, website := range websites {
-		responseBody = append(responseBody, getWebsitesResponse{
-			ID:     website.ID,
-			URL:    website.URL,
-			Healty: website.Healthy,
-		})
+		response := getWebsitesResponse{
+			ID:                    website.ID,
+			URL:                   storage.RedactURL(website.URL),
+			CheckType:             string(checker.TypeOf(website)),
+			Tags:                  website.Tags,
+			Healty:                website.Healthy,
+			State:                 string(website.State),
+			Maintenance:           maintenance.InMaintenance(windows, website, now),
+			Schedule:              website.Schedule,
+			Request:               newHTTPRequestResponse(website.Request),
+			Redirects:             newRedirectsResponse(website.Redirects),
+			Assertions:            newAssertionsResponse(website.Assertions),
+			TCP:                   newTCPResponse(website.TCP),
+			DNS:                   newDNSResponse(website.DNS),
+			GRPC:                  newGRPCResponse(website.GRPC),
+			WebSocket:             newWebSocketResponse(website.WebSocket),
+			Heartbeat:             newHeartbeatResponse(website.Heartbeat),
+			CertificateExpiryDays: website.CertificateExpiryDays,
+			Certificate:           newCertificateResponse(website.Certificate, now),
+			FailureThreshold:      website.FailureThreshold,
+			SuccessThreshold:      website.SuccessThreshold,
+		}
+		if website.State == "" {
+			response.State = string(storage.StateUnknown)
+		}
+		if website.Interval > 0 {
+			response.Interval = website.Interval.String()
+		}
+		if website.Timeout > 0 {
+			response.Timeout = website.Timeout.String()
+		}
+		if website.DegradedLatency > 0 {
+			response.DegradedLatency = website.DegradedLatency.String()
+		}
+		responseBody = append(responseBody, response)
 	}
 	w.Header().Add("Content-Type", "application/json")
 	if err = json.NewEncoder(w).Encode(&responseBody); err != nil {
@@ -71,7 +183,7 @@ func getWebsites(w http.ResponseWriter, r *http.Request, database storage.Databa
 	log.Print("successfully retrieve website records")
 }
 
-func createWebsite(w http.ResponseWriter, r *http.Request, database storage.Database) {
+func createWebsite(w http.ResponseWriter, r *http.Request, database storage.Database, defaultTimeout time.Duration) {
 	var requestBody createWebsiteRequest
 	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
 		log.Printf("unable to decode request body: %v", err)
@@ -84,35 +196,138 @@ func createWebsite(w http.ResponseWriter, r *http.Request, database storage.Data
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
-	_, err = url.ParseRequestURI(requestBody.URL)
+	checkType := checker.TypeOf(storage.Website{CheckType: storage.CheckType(requestBody.CheckType)})
+	if !checkers.Supports(checkType) {
+		log.Printf("unsupported check type: %s", requestBody.CheckType)
+		http.Error(w, fmt.Sprintf("invalid check_type. unsupported check type %q", requestBody.CheckType), http.StatusBadRequest)
+		return
+	}
+	interval, err := parseOptionalDuration(requestBody.Interval)
 	if err != nil {
-		log.Printf("unable to parse URL: %v with URL input: %s", err, requestBody.URL)
-		http.Error(w, "invalid URL. URL must be in form of absolute URL", http.StatusBadRequest)
+		log.Printf("unable to parse interval: %v with interval input: %s", err, requestBody.Interval)
+		http.Error(w, "invalid interval. interval must be a positive duration (e.g. 30s, 5m)", http.StatusBadRequest)
 		return
 	}
-	// TODO Set timeout to 800ms
-	var healthiness bool
-	response, err := httpGetRequestFunc(requestBody.URL)
+	timeout, err := parseOptionalDuration(requestBody.Timeout)
 	if err != nil {
-		log.Printf("url %s is not healthy: %v", requestBody.URL, err)
+		log.Printf("unable to parse timeout: %v with timeout input: %s", err, requestBody.Timeout)
+		http.Error(w, "invalid timeout. timeout must be a positive duration (e.g. 800ms, 2s)", http.StatusBadRequest)
+		return
+	}
+	if err = validateSchedule(requestBody.Schedule, interval); err != nil {
+		log.Printf("unable to validate schedule: %v with schedule input: %s", err, requestBody.Schedule)
+		http.Error(w, fmt.Sprintf("invalid schedule. %v", err), http.StatusBadRequest)
+		return
 	}
-	if err == nil && response.StatusCode == http.StatusOK {
-		healthiness = true
+	degradedLatency, err := parseOptionalDuration(requestBody.DegradedLatency)
+	if err != nil {
+		log.Printf("unable to parse degraded latency: %v with degraded latency input: %s", err, requestBody.DegradedLatency)
+		http.Error(w, "invalid degraded_latency. degraded_latency must be a positive duration (e.g. 500ms)", http.StatusBadRequest)
+		return
 	}
-	err = database.Save(storage.Website{
-		ID:      id.String(),
-		URL:     requestBody.URL,
-		Healthy: healthiness,
-	})
+	assertions, err := parseAssertions(requestBody.Assertions)
+	if err != nil {
+		log.Printf("unable to parse assertions: %v", err)
+		http.Error(w, fmt.Sprintf("invalid assertions: %v", err), http.StatusBadRequest)
+		return
+	}
+	if requestBody.FailureThreshold < 0 || requestBody.SuccessThreshold < 0 {
+		log.Printf("negative threshold: failure_threshold=%d success_threshold=%d", requestBody.FailureThreshold, requestBody.SuccessThreshold)
+		http.Error(w, "invalid threshold. failure_threshold and success_threshold must not be negative", http.StatusBadRequest)
+		return
+	}
+	if requestBody.CertificateExpiryDays < 0 {
+		log.Printf("negative certificate expiry days: %d", requestBody.CertificateExpiryDays)
+		http.Error(w, "invalid certificate_expiry_days. certificate_expiry_days must not be negative", http.StatusBadRequest)
+		return
+	}
+	website := storage.Website{
+		ID:                    id.String(),
+		URL:                   requestBody.URL,
+		Tags:                  requestBody.Tags,
+		CheckType:             checkType,
+		State:                 storage.StateUnknown,
+		Interval:              interval,
+		Timeout:               timeout,
+		Schedule:              requestBody.Schedule,
+		DegradedLatency:       degradedLatency,
+		Request:               parseHTTPRequest(requestBody.Request),
+		Redirects:             parseRedirectPolicy(requestBody.Redirects),
+		Assertions:            assertions,
+		TCP:                   parseTCPOptions(requestBody.TCP),
+		DNS:                   parseDNSOptions(requestBody.DNS),
+		GRPC:                  parseGRPCOptions(requestBody.GRPC),
+		WebSocket:             parseWebSocketOptions(requestBody.WebSocket),
+		CertificateExpiryDays: requestBody.CertificateExpiryDays,
+		FailureThreshold:      requestBody.FailureThreshold,
+		SuccessThreshold:      requestBody.SuccessThreshold,
+	}
+	if checkType == storage.CheckHeartbeat {
+		website.Heartbeat, err = newHeartbeat(requestBody.Heartbeat)
+		if err != nil {
+			log.Printf("unable to create heartbeat: %v", err)
+			http.Error(w, fmt.Sprintf("invalid heartbeat: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if err = checkers.Validate(website); err != nil {
+		log.Printf("unable to validate website: %v with URL input: %s", err, storage.RedactURL(requestBody.URL))
+		http.Error(w, fmt.Sprintf("invalid website: %v", err), http.StatusBadRequest)
+		return
+	}
+	windows, err := database.GetMaintenanceWindows()
+	if err != nil {
+		log.Printf("unable to get list of maintenance window from database: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	result := checkWithTimeout(r.Context(), website, defaultTimeout)
+	// unknown state means there is nothing to report yet, e.g. heartbeat
+	// waiting for its first ping
+	recorded := result.State != storage.StateUnknown
+	if recorded && maintenance.InMaintenance(windows, website, result.Time) {
+		// the result is kept, but it does not change state of the website
+		result.Maintenance = true
+	} else if recorded {
+		if !result.Healthy {
+			log.Printf("url %s is not healthy: %s", storage.RedactURL(requestBody.URL), result.Error)
+		}
+		website = state.Next(website, result.State, state.DefaultThresholds)
+		website.Certificate = result.Certificate
+	}
+	err = database.Save(website)
 	if err != nil {
 		log.Printf("unable to save to database: %v", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
+	if recorded {
+		if err = database.SaveCheckResult(result); err != nil {
+			log.Printf("unable to save check result to database: %v", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
 	log.Print("successfully store website to database")
 	w.WriteHeader(http.StatusCreated)
 }
 
+// checkWithTimeout checks the website within its own timeout, or within
+// defaultTimeout when the website has none, so the initial check never
+// blocks the request forever
+func checkWithTimeout(ctx context.Context, website storage.Website, defaultTimeout time.Duration) storage.CheckResult {
+	timeout := website.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return checkers.Check(ctx, website)
+}
+
 // deleteWebsite removes a website from database. delete action will ALWAYS
 // return success whether the record is found or not within database
 func deleteWebsite(w http.ResponseWriter, r *http.Request, database storage.Database) {
@@ -135,3 +350,38 @@ func deleteWebsite(w http.ResponseWriter, r *http.Request, database storage.Data
 	log.Printf("success delete website with id: %s", websiteID)
 	w.WriteHeader(http.StatusOK)
 }
+
+// validateSchedule validates optional cron expression, it can not be used
+// along with interval and must match at least once
+func validateSchedule(expression string, interval time.Duration) error {
+	if expression == "" {
+		return nil
+	}
+	if interval > 0 {
+		return fmt.Errorf("schedule can not be used along with interval")
+	}
+	schedule, err := cron.Parse(expression)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(timeNowFunc()).IsZero() {
+		return fmt.Errorf("schedule %q never matches", expression)
+	}
+	return nil
+}
+
+// parseOptionalDuration parses a positive duration, empty value results in
+// zero duration
+func parseOptionalDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("duration must be positive, got %s", value)
+	}
+	return duration, nil
+}
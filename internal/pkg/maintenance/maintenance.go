@@ -0,0 +1,78 @@
+package maintenance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/cron"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// Validate validates a maintenance window, it must apply either to a website
+// or to a tag, and be either one-off (start and end) or recurring (schedule
+// and duration)
+func Validate(window storage.MaintenanceWindow) error {
+	if (window.WebsiteID == "") == (window.Tag == "") {
+		return fmt.Errorf("either website ID or tag is required")
+	}
+	oneOff := !window.Start.IsZero() || !window.End.IsZero()
+	recurring := window.Schedule != "" || window.Duration != 0
+	if oneOff == recurring {
+		return fmt.Errorf("either start and end (one-off) or schedule and duration (recurring) is required")
+	}
+	if oneOff {
+		if window.Start.IsZero() || window.End.IsZero() {
+			return fmt.Errorf("both start and end are required")
+		}
+		if !window.End.After(window.Start) {
+			return fmt.Errorf("end must be after start")
+		}
+		return nil
+	}
+	if window.Duration <= 0 {
+		return fmt.Errorf("duration must be positive, got %s", window.Duration)
+	}
+	if _, err := cron.Parse(window.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule: %v", err)
+	}
+	return nil
+}
+
+// Active reports whether the window is in effect at t. A recurring window is
+// in effect when one of its starts happened within its duration before t
+func Active(window storage.MaintenanceWindow, t time.Time) bool {
+	if window.Schedule == "" {
+		return !t.Before(window.Start) && t.Before(window.End)
+	}
+	schedule, err := cron.Parse(window.Schedule)
+	if err != nil {
+		return false
+	}
+	start := schedule.Next(t.Add(-window.Duration))
+	return !start.IsZero() && !start.After(t)
+}
+
+// AppliesTo reports whether the window applies to the website, either by its
+// ID or by one of its tags
+func AppliesTo(window storage.MaintenanceWindow, website storage.Website) bool {
+	if window.WebsiteID != "" {
+		return window.WebsiteID == website.ID
+	}
+	for _, tag := range website.Tags {
+		if tag == window.Tag {
+			return true
+		}
+	}
+	return false
+}
+
+// InMaintenance reports whether the website is within any of the windows at
+// t
+func InMaintenance(windows []storage.MaintenanceWindow, website storage.Website, t time.Time) bool {
+	for _, window := range windows {
+		if AppliesTo(window, website) && Active(window, t) {
+			return true
+		}
+	}
+	return false
+}
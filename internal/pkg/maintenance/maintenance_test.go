@@ -0,0 +1,112 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+var (
+	start = time.Date(2019, 3, 8, 22, 0, 0, 0, time.UTC)
+	end   = time.Date(2019, 3, 8, 23, 0, 0, 0, time.UTC)
+)
+
+func TestValidate(t *testing.T) {
+	// arrange
+	validateTests := []struct {
+		testName string
+		window   storage.MaintenanceWindow
+		valid    bool
+	}{
+		{"one-off", storage.MaintenanceWindow{WebsiteID: "123", Start: start, End: end}, true},
+		{"recurring", storage.MaintenanceWindow{Tag: "database", Schedule: "0 2 * * sun", Duration: time.Hour}, true},
+		{"no website nor tag", storage.MaintenanceWindow{Start: start, End: end}, false},
+		{"both website and tag", storage.MaintenanceWindow{WebsiteID: "123", Tag: "database", Start: start, End: end}, false},
+		{"no period", storage.MaintenanceWindow{WebsiteID: "123"}, false},
+		{"both one-off and recurring", storage.MaintenanceWindow{WebsiteID: "123", Start: start, End: end, Schedule: "@daily", Duration: time.Hour}, false},
+		{"no end", storage.MaintenanceWindow{WebsiteID: "123", Start: start}, false},
+		{"end before start", storage.MaintenanceWindow{WebsiteID: "123", Start: end, End: start}, false},
+		{"no duration", storage.MaintenanceWindow{WebsiteID: "123", Schedule: "@daily"}, false},
+		{"invalid schedule", storage.MaintenanceWindow{WebsiteID: "123", Schedule: "daily", Duration: time.Hour}, false},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			err := Validate(tt.window)
+
+			// acceptance
+			if (err == nil) != tt.valid {
+				t.Errorf("expected valid to be %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestActive(t *testing.T) {
+	// arrange
+	oneOff := storage.MaintenanceWindow{WebsiteID: "123", Start: start, End: end}
+	// every Sunday from 02:00 until 03:30, 2019-03-10 is Sunday
+	recurring := storage.MaintenanceWindow{Tag: "database", Schedule: "0 2 * * sun", Duration: 90 * time.Minute}
+	activeTests := []struct {
+		testName string
+		window   storage.MaintenanceWindow
+		at       time.Time
+		expected bool
+	}{
+		{"before one-off", oneOff, start.Add(-time.Second), false},
+		{"start of one-off", oneOff, start, true},
+		{"within one-off", oneOff, start.Add(30 * time.Minute), true},
+		{"end of one-off", oneOff, end, false},
+		{"before recurring", recurring, time.Date(2019, 3, 10, 1, 59, 59, 0, time.UTC), false},
+		{"start of recurring", recurring, time.Date(2019, 3, 10, 2, 0, 0, 0, time.UTC), true},
+		{"within recurring", recurring, time.Date(2019, 3, 10, 3, 29, 59, 0, time.UTC), true},
+		{"end of recurring", recurring, time.Date(2019, 3, 10, 3, 30, 0, 0, time.UTC), false},
+		{"another day", recurring, time.Date(2019, 3, 11, 2, 30, 0, 0, time.UTC), false},
+		{"next week", recurring, time.Date(2019, 3, 17, 2, 30, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range activeTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			actual := Active(tt.window, tt.at)
+
+			// acceptance
+			if actual != tt.expected {
+				t.Errorf("expected active to be %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestInMaintenance(t *testing.T) {
+	// arrange
+	windows := []storage.MaintenanceWindow{
+		{WebsiteID: "123", Start: start, End: end},
+		{Tag: "database", Start: start.Add(time.Hour), End: end.Add(time.Hour)},
+	}
+	maintenanceTests := []struct {
+		testName string
+		website  storage.Website
+		at       time.Time
+		expected bool
+	}{
+		{"by website ID", storage.Website{ID: "123"}, start, true},
+		{"other website", storage.Website{ID: "456"}, start, false},
+		{"by tag", storage.Website{ID: "456", Tags: []string{"web", "database"}}, end, true},
+		{"outside window of tag", storage.Website{ID: "456", Tags: []string{"database"}}, start, false},
+	}
+
+	for _, tt := range maintenanceTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// action
+			actual := InMaintenance(windows, tt.website, tt.at)
+
+			// acceptance
+			if actual != tt.expected {
+				t.Errorf("expected in maintenance to be %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
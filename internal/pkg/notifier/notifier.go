@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// Notifier reports state transitions of websites
+type Notifier interface {
+	// Notify reports the transition. Webhook blocks until the notification
+	// is delivered or given up, while Queue only queues it. Giving up is not
+	// an error of the caller, it is recorded within the delivery log instead
+	Notify(ctx context.Context, notification storage.Notification)
+}
+
+// ShouldNotify reports whether a transition of a website from one state to
+// another is worth notifying. The first state of a website is only notified
+// when the website is down, e.g. heartbeat that never pinged
+func ShouldNotify(from, to storage.State) bool {
+	if from == to {
+		return false
+	}
+	if from == "" || from == storage.StateUnknown {
+		return to == storage.StateDown
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+func TestShouldNotify(t *testing.T) {
+	shouldNotifyTests := []struct {
+		from     storage.State
+		to       storage.State
+		expected bool
+	}{
+		{storage.StateUp, storage.StateDown, true},
+		{storage.StateDown, storage.StateUp, true},
+		{storage.StateUp, storage.StateDegraded, true},
+		{storage.StateDown, storage.StateDown, false},
+		{storage.StateUnknown, storage.StateUp, false},
+		{storage.StateUnknown, storage.StateDown, true},
+		{"", storage.StateDown, true},
+	}
+
+	for _, tt := range shouldNotifyTests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			if actual := ShouldNotify(tt.from, tt.to); actual != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package notifier
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// DefaultQueueSize number of notifications a queue holds before it drops new
+// ones, unless configured otherwise
+const DefaultQueueSize = 100
+
+// Queue notifier that hands notifications over to another notifier on its
+// own goroutine, so reporting a transition never waits for the delivery.
+// Notifications are delivered one by one in the order they are reported
+type Queue struct {
+	notifier      Notifier
+	notifications chan storage.Notification
+	mutex         sync.Mutex
+	// stop and done are nil unless the queue is running
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewQueue initialize queue of notifications delivered by notifier, holding
+// at most size notifications (zero or negative means DefaultQueueSize). It
+// delivers nothing until it is started
+func NewQueue(notifier Notifier, size int) *Queue {
+	if size < 1 {
+		size = DefaultQueueSize
+	}
+	return &Queue{
+		notifier:      notifier,
+		notifications: make(chan storage.Notification, size),
+	}
+}
+
+// Notify implements Notifier, it queues the notification and returns right
+// away. ctx is ignored since the notification is delivered later within
+// context of Start. The notification is dropped when the queue is full
+func (queue *Queue) Notify(ctx context.Context, notification storage.Notification) {
+	select {
+	case queue.notifications <- notification:
+	default:
+		log.Printf("notification queue is full, notification of website with URL: %s changed to %s is dropped",
+			notification.URL, notification.NewState)
+	}
+}
+
+// Start delivers queued notifications on the background until Stop is called
+// or ctx is done. Cancelling ctx gives up the delivery in progress and the
+// ones still queued. Starting a running queue does nothing
+func (queue *Queue) Start(ctx context.Context) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if queue.stop != nil {
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	queue.stop, queue.done = stop, done
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case notification := <-queue.notifications:
+				queue.notifier.Notify(ctx, notification)
+			case <-stop:
+				queue.drain(ctx)
+				return
+			case <-ctx.Done():
+				queue.drain(ctx)
+				return
+			}
+		}
+	}()
+}
+
+// drain delivers notifications that are still queued
+func (queue *Queue) drain(ctx context.Context) {
+	for {
+		select {
+		case notification := <-queue.notifications:
+			queue.notifier.Notify(ctx, notification)
+		default:
+			return
+		}
+	}
+}
+
+// Stop blocks until notifications queued so far are delivered (or given up)
+// and stops the queue. Stopping a queue that is not running does nothing
+func (queue *Queue) Stop() {
+	queue.mutex.Lock()
+	stop, done := queue.stop, queue.done
+	queue.stop, queue.done = nil, nil
+	queue.mutex.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
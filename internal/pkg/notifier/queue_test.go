@@ -0,0 +1,116 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// blockingNotifier records notifications, every delivery waits until release
+// is closed
+type blockingNotifier struct {
+	release       chan struct{}
+	mutex         sync.Mutex
+	notifications []storage.Notification
+}
+
+func (notifier *blockingNotifier) Notify(ctx context.Context, notification storage.Notification) {
+	<-notifier.release
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	notifier.notifications = append(notifier.notifications, notification)
+}
+
+func (notifier *blockingNotifier) delivered() []storage.Notification {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	return append([]storage.Notification(nil), notifier.notifications...)
+}
+
+func TestQueueDeliversOnBackground(t *testing.T) {
+	// arrange
+	blocking := &blockingNotifier{release: make(chan struct{})}
+	queue := NewQueue(blocking, 10)
+	queue.Start(context.Background())
+	states := []storage.State{storage.StateDown, storage.StateUp, storage.StateDegraded}
+
+	// action
+	returned := make(chan struct{})
+	go func() {
+		for _, state := range states {
+			queue.Notify(context.Background(), storage.Notification{WebsiteID: "123", NewState: state})
+		}
+		close(returned)
+	}()
+
+	// acceptance
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected notify to return while delivery is blocked")
+	}
+	close(blocking.release)
+	queue.Stop()
+	delivered := blocking.delivered()
+	if len(delivered) != len(states) {
+		t.Fatalf("expected queued notifications to be delivered on stop, got %d", len(delivered))
+	}
+	for i, state := range states {
+		if delivered[i].NewState != state {
+			t.Errorf("expected notification %d to be %s, got %s", i, state, delivered[i].NewState)
+		}
+	}
+}
+
+func TestQueueDropsNotificationsOnceFull(t *testing.T) {
+	// arrange
+	blocking := &blockingNotifier{release: make(chan struct{})}
+	queue := NewQueue(blocking, 2)
+
+	// action
+	for i := 0; i < 5; i++ {
+		queue.Notify(context.Background(), storage.Notification{WebsiteID: "123"})
+	}
+
+	// acceptance
+	close(blocking.release)
+	queue.Start(context.Background())
+	queue.Stop()
+	if delivered := blocking.delivered(); len(delivered) != 2 {
+		t.Errorf("expected 2 notifications within full queue to be delivered, got %d", len(delivered))
+	}
+}
+
+func TestQueueGivesUpOnceCancelled(t *testing.T) {
+	// arrange
+	var cancelled []error
+	var mutex sync.Mutex
+	queue := NewQueue(notifierFunc(func(ctx context.Context, notification storage.Notification) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		cancelled = append(cancelled, ctx.Err())
+	}), 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// action
+	queue.Notify(context.Background(), storage.Notification{WebsiteID: "123"})
+	queue.Start(ctx)
+	queue.Stop()
+
+	// acceptance
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(cancelled) != 1 || cancelled[0] != context.Canceled {
+		t.Errorf("expected queued notification to be delivered within cancelled context, got %v", cancelled)
+	}
+}
+
+type notifierFunc func(ctx context.Context, notification storage.Notification)
+
+func (f notifierFunc) Notify(ctx context.Context, notification storage.Notification) {
+	f(ctx, notification)
+}
@@ -0,0 +1,192 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/backoff"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+const (
+	// defaultTimeout timeout of every attempt of a delivery unless configured
+	// otherwise
+	defaultTimeout = 5 * time.Second
+	// defaultRetryBackoff delay before the first retry unless configured
+	// otherwise
+	defaultRetryBackoff = time.Second
+	// maxResponseBodySize maximum number of bytes of the response read before
+	// the connection is reused
+	maxResponseBodySize = 64 << 10
+)
+
+var (
+	// sleepFunc waits between two attempts of a delivery, replaced by tests
+	sleepFunc = backoff.Sleep
+	// timeNowFunc source of time of deliveries, replaced by tests
+	timeNowFunc = time.Now
+)
+
+// Config configurations of webhook deliveries
+type Config struct {
+	// Retries maximum number of retries of a failed delivery. Zero means no
+	// retries
+	Retries int
+	// RetryBackoff delay before the first retry, doubled on every following
+	// retry. Zero means one second
+	RetryBackoff time.Duration
+	// Timeout timeout of every attempt. Zero means five seconds
+	Timeout time.Duration
+}
+
+// payload JSON body posted to the webhook
+type payload struct {
+	WebsiteID string    `json:"website_id"`
+	URL       string    `json:"url"`
+	OldState  string    `json:"old_state"`
+	NewState  string    `json:"new_state"`
+	LastError string    `json:"last_error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Webhook notifier that posts every notification as JSON to an HTTP
+// endpoint. Every delivery, whether it succeeded or not, is recorded within
+// the delivery log of database
+type Webhook struct {
+	url string
+	// origin scheme and host of url recorded within the delivery log, the
+	// path or query of webhook URL often holds a secret token
+	origin   string
+	client   *http.Client
+	database storage.Database
+	config   Config
+}
+
+// NewWebhook initialize webhook notifier posting to rawURL, which must be an
+// absolute HTTP(S) URL
+func NewWebhook(rawURL string, database storage.Database, config Config) (*Webhook, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %v", err)
+	}
+	if (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %q, absolute http or https URL is required", rawURL)
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaultRetryBackoff
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	return &Webhook{
+		url:      rawURL,
+		origin:   RedactWebhookURL(rawURL),
+		client:   &http.Client{},
+		database: database,
+		config:   config,
+	}, nil
+}
+
+// Notify delivers the notification to the webhook, a failed delivery is
+// retried up to number of retries of config with exponential backoff. No
+// more attempts are made once ctx is done
+func (webhook *Webhook) Notify(ctx context.Context, notification storage.Notification) {
+	delivery := webhook.deliver(ctx, notification)
+	if delivery.Delivered {
+		log.Printf("notification of website with URL: %s changed to %s is delivered to webhook", notification.URL, notification.NewState)
+	} else {
+		log.Printf("unable to deliver notification of website with URL: %s changed to %s after %d attempts: %s",
+			notification.URL, notification.NewState, delivery.Attempts, delivery.Error)
+	}
+	if err := webhook.database.SaveDelivery(delivery); err != nil {
+		log.Printf("unable to save delivery to database: %v", err)
+	}
+}
+
+func (webhook *Webhook) deliver(ctx context.Context, notification storage.Notification) storage.Delivery {
+	delivery := storage.Delivery{Notification: notification, Webhook: webhook.origin}
+	body, err := json.Marshal(payload{
+		WebsiteID: notification.WebsiteID,
+		URL:       storage.RedactURL(notification.URL),
+		OldState:  string(notification.OldState),
+		NewState:  string(notification.NewState),
+		LastError: notification.LastError,
+		Timestamp: notification.Time,
+	})
+	if err != nil {
+		delivery.Error = fmt.Sprintf("unable to encode payload: %v", err)
+		delivery.Time = timeNowFunc()
+		return delivery
+	}
+	for attempt := 1; ; attempt++ {
+		delivery.Attempts = attempt
+		delivery.StatusCode, err = webhook.post(ctx, body)
+		delivery.Time = timeNowFunc()
+		if err == nil {
+			delivery.Error = ""
+			delivery.Delivered = true
+			return delivery
+		}
+		delivery.Error = err.Error()
+		if !retryable(delivery.StatusCode) || attempt > webhook.config.Retries {
+			return delivery
+		}
+		delay := backoff.Delay(webhook.config.RetryBackoff, attempt)
+		log.Printf("delivery of notification to webhook failed attempt %d: %s, retrying in %s", attempt, delivery.Error, delay)
+		if err = sleepFunc(ctx, delay); err != nil {
+			return delivery
+		}
+	}
+}
+
+// RedactWebhookURL returns only scheme and host of webhook URL, so it can be
+// output without leaking a secret within its userinfo, path or query
+func RedactWebhookURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.Host == "" {
+		return ""
+	}
+	return (&url.URL{Scheme: parsedURL.Scheme, Host: parsedURL.Host}).String()
+}
+
+// post posts body to the webhook within timeout of config, and returns
+// status code of the response, zero when there is no response
+func (webhook *Webhook) post(ctx context.Context, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, webhook.config.Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := webhook.client.Do(request)
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			// the error is recorded, so it must not hold the full URL
+			urlErr.URL = webhook.origin
+		}
+		return 0, err
+	}
+	defer response.Body.Close()
+	// drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, maxResponseBodySize))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// retryable reports whether a delivery failed with status code is worth
+// retrying. Failures without response, too many requests and server errors
+// are temporary, other responses mean the webhook rejects the notification
+func retryable(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
@@ -0,0 +1,77 @@
+package state
+
+import "github.com/ajiyakin/gohealthz/internal/pkg/storage"
+
+// Thresholds number of consecutive check results required before the state
+// of a website flips
+type Thresholds struct {
+	// Failure number of consecutive failed checks before an up (or
+	// degraded) website goes down
+	Failure int
+	// Success number of consecutive successful checks before a down website
+	// goes up (or degraded)
+	Success int
+}
+
+// DefaultThresholds flips the state on the first check result that differs
+var DefaultThresholds = Thresholds{Failure: 1, Success: 1}
+
+// Next returns the website with its state and consecutive counters updated
+// based on observed state of its latest check. Transitions are:
+//
+//	unknown          -> up, degraded or down on the first check result
+//	up <-> degraded  on every check result
+//	up/degraded      -> down after Failure consecutive failed checks
+//	down             -> up/degraded after Success consecutive successful checks
+//
+// Thresholds of the website take precedence over defaults, zero (or
+// negative) threshold falls back to defaults
+func Next(website storage.Website, observed storage.State, defaults Thresholds) storage.Website {
+	thresholds := thresholdsOf(website, defaults)
+	current := website.State
+	if current == "" {
+		current = storage.StateUnknown
+	}
+
+	if observed == storage.StateDown {
+		website.ConsecutiveFailures++
+		website.ConsecutiveSuccesses = 0
+	} else {
+		website.ConsecutiveSuccesses++
+		website.ConsecutiveFailures = 0
+	}
+
+	switch {
+	case current == storage.StateUnknown:
+		website.State = observed
+	case observed == storage.StateDown && current != storage.StateDown:
+		if website.ConsecutiveFailures >= thresholds.Failure {
+			website.State = storage.StateDown
+		}
+	case observed != storage.StateDown && current == storage.StateDown:
+		if website.ConsecutiveSuccesses >= thresholds.Success {
+			website.State = observed
+		}
+	case observed != storage.StateDown:
+		website.State = observed
+	}
+	website.Healthy = website.State == storage.StateUp || website.State == storage.StateDegraded
+	return website
+}
+
+func thresholdsOf(website storage.Website, defaults Thresholds) Thresholds {
+	thresholds := Thresholds{Failure: website.FailureThreshold, Success: website.SuccessThreshold}
+	if thresholds.Failure <= 0 {
+		thresholds.Failure = defaults.Failure
+	}
+	if thresholds.Success <= 0 {
+		thresholds.Success = defaults.Success
+	}
+	if thresholds.Failure <= 0 {
+		thresholds.Failure = 1
+	}
+	if thresholds.Success <= 0 {
+		thresholds.Success = 1
+	}
+	return thresholds
+}
@@ -0,0 +1,104 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+func TestNextFromUnknown(t *testing.T) {
+	// arrange
+	observedTests := []storage.State{storage.StateUp, storage.StateDegraded, storage.StateDown}
+
+	for _, observed := range observedTests {
+		t.Run(string(observed), func(t *testing.T) {
+			website := storage.Website{ID: "123", State: storage.StateUnknown, FailureThreshold: 3, SuccessThreshold: 3}
+
+			// action
+			actual := Next(website, observed, DefaultThresholds)
+
+			// acceptance
+			if actual.State != observed {
+				t.Errorf("expected first check result to decide the state %s, got %s", observed, actual.State)
+			}
+		})
+	}
+}
+
+func TestNextTransitions(t *testing.T) {
+	// arrange
+	up, degraded, down := storage.StateUp, storage.StateDegraded, storage.StateDown
+	transitionTests := []struct {
+		testName   string
+		thresholds Thresholds
+		initial    storage.State
+		observed   []storage.State
+		expected   []storage.State
+	}{
+		{
+			"flip immediately with default thresholds",
+			DefaultThresholds,
+			up,
+			[]storage.State{down, up, down},
+			[]storage.State{down, up, down},
+		}, {
+			"go down after consecutive failures",
+			Thresholds{Failure: 3, Success: 1},
+			up,
+			[]storage.State{down, down, up, down, down, down},
+			[]storage.State{up, up, up, up, up, down},
+		}, {
+			"recover after consecutive successes",
+			Thresholds{Failure: 1, Success: 2},
+			down,
+			[]storage.State{up, down, up, up, up},
+			[]storage.State{down, down, down, up, up},
+		}, {
+			"recover to degraded",
+			Thresholds{Failure: 1, Success: 2},
+			down,
+			[]storage.State{up, degraded},
+			[]storage.State{down, degraded},
+		}, {
+			"switch between up and degraded without threshold",
+			Thresholds{Failure: 3, Success: 3},
+			up,
+			[]storage.State{degraded, up},
+			[]storage.State{degraded, up},
+		},
+	}
+
+	for _, tt := range transitionTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			website := storage.Website{ID: "123", State: tt.initial}
+			for index, observed := range tt.observed {
+				// action
+				website = Next(website, observed, tt.thresholds)
+
+				// acceptance
+				if website.State != tt.expected[index] {
+					t.Errorf("check %d: expected state %s, got %s", index+1, tt.expected[index], website.State)
+				}
+				if website.Healthy != (website.State != down) {
+					t.Errorf("check %d: expected healthy %v for state %s", index+1, website.State != down, website.State)
+				}
+			}
+		})
+	}
+}
+
+func TestNextPrefersThresholdsOfWebsite(t *testing.T) {
+	// arrange
+	website := storage.Website{ID: "123", State: storage.StateUp, FailureThreshold: 2}
+
+	// action
+	website = Next(website, storage.StateDown, Thresholds{Failure: 1, Success: 1})
+
+	// acceptance
+	if website.State != storage.StateUp {
+		t.Errorf("expected website to stay up until its own failure threshold, got %s", website.State)
+	}
+	if website.ConsecutiveFailures != 1 {
+		t.Errorf("expected 1 consecutive failure, got %d", website.ConsecutiveFailures)
+	}
+}
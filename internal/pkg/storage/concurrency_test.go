@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+const (
+	stressWorkers    = 16
+	stressIterations = 200
+)
+
+// databaseDrivers list of every Database implementation that must be safe
+// for concurrent use. Run with `go test -race` to detect data races
+func databaseDrivers(t *testing.T) map[string]Database {
+	fileDatabase, err := NewFileDatabase(filepath.Join(t.TempDir(), "gohealthz.db"), DefaultHistoryLimit, DefaultHistoryRetention)
+	if err != nil {
+		t.Fatalf("unable to open file database: %v", err)
+	}
+	t.Cleanup(func() { fileDatabase.Close() })
+	return map[string]Database{
+		"memory": NewInMemoryDatabase(),
+		"file":   fileDatabase,
+	}
+}
+
+func TestDatabaseConcurrentReadWrite(t *testing.T) {
+	for name, db := range databaseDrivers(t) {
+		t.Run(name, func(t *testing.T) {
+			// arrange
+			var wg sync.WaitGroup
+
+			// action
+			for worker := 0; worker < stressWorkers; worker++ {
+				wg.Add(1)
+				go func(worker int) {
+					defer wg.Done()
+					for i := 0; i < stressIterations; i++ {
+						id := fmt.Sprintf("%d-%d", worker, i%10)
+						if err := db.Save(Website{ID: id, URL: "http://example.com", Healthy: i%2 == 0}); err != nil {
+							t.Errorf("unable to save website: %v", err)
+						}
+						if _, err := db.GetByID(id); err != nil && err != ErrNotFound {
+							t.Errorf("unable to get website by ID: %v", err)
+						}
+						if _, err := db.Get(); err != nil {
+							t.Errorf("unable to get websites: %v", err)
+						}
+						if i%3 == 0 {
+							if err := db.Delete(id); err != nil {
+								t.Errorf("unable to delete website: %v", err)
+							}
+						}
+					}
+				}(worker)
+			}
+			wg.Wait()
+
+			// acceptance
+			websites, err := db.Get()
+			if err != nil {
+				t.Errorf("unable to get websites: %v", err)
+			}
+			if len(websites) > stressWorkers*10 {
+				t.Errorf("expected at most %d websites, got %d", stressWorkers*10, len(websites))
+			}
+		})
+	}
+}
+
+func TestDatabaseConcurrentUpdateSameWebsite(t *testing.T) {
+	for name, db := range databaseDrivers(t) {
+		t.Run(name, func(t *testing.T) {
+			// arrange
+			var wg sync.WaitGroup
+
+			// action
+			for worker := 0; worker < stressWorkers; worker++ {
+				wg.Add(1)
+				go func(worker int) {
+					defer wg.Done()
+					for i := 0; i < stressIterations; i++ {
+						if err := db.Save(Website{ID: "123", URL: fmt.Sprintf("http://%d.example.com", worker)}); err != nil {
+							t.Errorf("unable to save website: %v", err)
+						}
+						website, err := db.GetByID("123")
+						if err != nil {
+							t.Errorf("unable to get website by ID: %v", err)
+						}
+						// modifying a copy must not affect other readers
+						website.Healthy = !website.Healthy
+					}
+				}(worker)
+			}
+			wg.Wait()
+
+			// acceptance
+			websites, err := db.Get()
+			if err != nil {
+				t.Errorf("unable to get websites: %v", err)
+			}
+			if len(websites) != 1 {
+				t.Errorf("expected exactly 1 website, got %d", len(websites))
+			}
+		})
+	}
+}
+
+func TestDatabaseGetReturnsCopies(t *testing.T) {
+	for name, db := range databaseDrivers(t) {
+		t.Run(name, func(t *testing.T) {
+			// arrange
+			if err := db.Save(Website{ID: "123", URL: "http://example.com", Healthy: true}); err != nil {
+				t.Errorf("unable to save website: %v", err)
+			}
+
+			// action
+			websites, err := db.Get()
+			if err != nil {
+				t.Errorf("unable to get websites: %v", err)
+			}
+			websites[0].Healthy = false
+			websites[0].URL = "http://modified.example.com"
+
+			// acceptance
+			actual, err := db.GetByID("123")
+			if err != nil {
+				t.Errorf("unable to get website by ID: %v", err)
+			}
+			expected := Website{ID: "123", URL: "http://example.com", Healthy: true}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("expected %#v got %#v", expected, actual)
+			}
+		})
+	}
+}
+
+func TestDatabaseConcurrentUpdatesAreNotLost(t *testing.T) {
+	for name, db := range databaseDrivers(t) {
+		t.Run(name, func(t *testing.T) {
+			// arrange
+			if err := db.Save(Website{ID: "123", URL: "http://example.com"}); err != nil {
+				t.Errorf("unable to save website: %v", err)
+			}
+			var wg sync.WaitGroup
+
+			// action
+			for worker := 0; worker < stressWorkers; worker++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < stressIterations/10; i++ {
+						_, err := db.Update("123", func(website Website) Website {
+							website.ConsecutiveFailures++
+							return website
+						})
+						if err != nil {
+							t.Errorf("unable to update website: %v", err)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+
+			// acceptance
+			website, err := db.GetByID("123")
+			if err != nil {
+				t.Errorf("unable to get website by ID: %v", err)
+			}
+			if expected := stressWorkers * stressIterations / 10; website.ConsecutiveFailures != expected {
+				t.Errorf("expected %d updates, got %d", expected, website.ConsecutiveFailures)
+			}
+			if _, err = db.Update("unknown", func(website Website) Website { return website }); err != ErrNotFound {
+				t.Errorf("expected error not found, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	fileOperationSave   = "save"
+	fileOperationDelete = "delete"
+	fileOperationResult = "result"
+	// operations of maintenance windows
+	fileOperationSaveWindow   = "save_window"
+	fileOperationDeleteWindow = "delete_window"
+	// operation of notification delivery log
+	fileOperationDelivery = "delivery"
+
+	// fileCompactionMinimumRecords minimum number of records appended to the
+	// log file before it is compacted while the database is open
+	fileCompactionMinimumRecords = 10000
+)
+
+var (
+	// writeRecordFunc writes a record appended to the log file, replaced by
+	// tests
+	writeRecordFunc = writeRecord
+)
+
+// fileRecord a single entry of the append-only log written by FileDatabase
+type fileRecord struct {
+	Operation string             `json:"op"`
+	Website   Website            `json:"website"`
+	Result    *CheckResult       `json:"result,omitempty"`
+	Window    *MaintenanceWindow `json:"window,omitempty"`
+	Delivery  *Delivery          `json:"delivery,omitempty"`
+}
+
+// FileDatabase storage that keeps records within memory and persists every
+// change to an append-only log file on local disk, so records survive a
+// restart (or crash) of the application. It is safe for concurrent use by
+// multiple goroutines
+type FileDatabase struct {
+	// mutex serializes writes so the order of records within the log file is
+	// the same as the order they are applied to memory
+	mutex  sync.Mutex
+	memory *InMemoryDatabase
+	path   string
+	file   *os.File
+	// appended number of records appended to the log file since it was
+	// compacted
+	appended int
+	// failed set when a partially written record can not be removed from
+	// the log file, any further write is refused since it would follow a
+	// corrupted record
+	failed error
+}
+
+// NewFileDatabase opens (or creates) the log file located at path, replays
+// it to restore the records, and compacts it so it only contains the latest
+// state of every website. At most historyLimit check results are kept for
+// every website, none of them older than historyRetention before the latest
+// one (zero or negative means unlimited)
+func NewFileDatabase(path string, historyLimit int, historyRetention time.Duration) (*FileDatabase, error) {
+	memory := NewInMemoryDatabase()
+	memory.SetHistoryLimit(historyLimit)
+	memory.SetHistoryRetention(historyRetention)
+	if err := replayFile(path, memory); err != nil {
+		return nil, err
+	}
+	database := &FileDatabase{
+		memory: memory,
+		path:   path,
+	}
+	if err := database.compact(); err != nil {
+		return nil, err
+	}
+	return database, nil
+}
+
+// compact compacts the log file and (re)opens it for appending new records.
+// The current log file is kept open until the compacted one replaces it, so
+// the database stays writable when compaction fails
+func (database *FileDatabase) compact() error {
+	file, err := compactFile(database.path, database.memory)
+	if err != nil {
+		return err
+	}
+	if database.file != nil {
+		if err = database.file.Close(); err != nil {
+			log.Printf("unable to close replaced database file %s: %v", database.path, err)
+		}
+	}
+	database.file = file
+	database.appended = 0
+	return nil
+}
+
+// replayFile applies every record of the log file into memory. A partially
+// written (truncated) last line, which may happen when the process crashes in
+// the middle of a write, is ignored
+func replayFile(path string, memory *InMemoryDatabase) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to open database file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// the last line has no line terminator means it was not
+			// completely written
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read database file %s: %v", path, err)
+		}
+		var record fileRecord
+		if err = json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("corrupted record in database file %s: %v", path, err)
+		}
+		switch record.Operation {
+		case fileOperationSave:
+			err = memory.Save(record.Website)
+		case fileOperationDelete:
+			err = memory.Delete(record.Website.ID)
+		case fileOperationResult:
+			if record.Result == nil {
+				err = fmt.Errorf("missing check result in database file %s", path)
+				break
+			}
+			err = memory.SaveCheckResult(*record.Result)
+			if err == ErrNotFound {
+				// result of a website that is deleted afterwards
+				err = nil
+			}
+		case fileOperationSaveWindow:
+			if record.Window == nil {
+				err = fmt.Errorf("missing maintenance window in database file %s", path)
+				break
+			}
+			err = memory.SaveMaintenanceWindow(*record.Window)
+		case fileOperationDeleteWindow:
+			if record.Window == nil {
+				err = fmt.Errorf("missing maintenance window in database file %s", path)
+				break
+			}
+			err = memory.DeleteMaintenanceWindow(record.Window.ID)
+		case fileOperationDelivery:
+			if record.Delivery == nil {
+				err = fmt.Errorf("missing delivery in database file %s", path)
+				break
+			}
+			err = memory.SaveDelivery(*record.Delivery)
+		default:
+			err = fmt.Errorf("unknown operation %q in database file %s", record.Operation, path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// compactFile rewrites the log file so it only contains a save record for
+// every website currently stored within memory, followed by its check
+// history, a save record for every maintenance window and the delivery log.
+// The new content is written to a temporary file first and renamed, so the
+// old log stays intact if the process crashes (or compaction fails) halfway.
+// The new log file is returned open for appending
+func compactFile(path string, memory *InMemoryDatabase) (*os.File, error) {
+	temporaryPath := path + ".tmp"
+	file, err := os.OpenFile(temporaryPath, os.O_WRONLY|os.O_TRUNC|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create database file %s: %v", temporaryPath, err)
+	}
+	if err = writeSnapshot(file, memory); err != nil {
+		file.Close()
+		os.Remove(temporaryPath)
+		return nil, err
+	}
+	if err = os.Rename(temporaryPath, path); err != nil {
+		file.Close()
+		os.Remove(temporaryPath)
+		return nil, fmt.Errorf("unable to replace database file %s: %v", path, err)
+	}
+	return file, nil
+}
+
+// writeSnapshot writes records of everything stored within memory to file
+// and makes sure they reach the disk
+func writeSnapshot(file *os.File, memory *InMemoryDatabase) error {
+	websites, err := memory.Get()
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for _, website := range websites {
+		if err = writeRecord(writer, fileRecord{Operation: fileOperationSave, Website: website}); err != nil {
+			return err
+		}
+		results, err := memory.GetCheckResults(website.ID, time.Time{}, time.Time{})
+		if err != nil {
+			return err
+		}
+		for i := range results {
+			if err = writeRecord(writer, fileRecord{Operation: fileOperationResult, Result: &results[i]}); err != nil {
+				return err
+			}
+		}
+	}
+	windows, err := memory.GetMaintenanceWindows()
+	if err != nil {
+		return err
+	}
+	for i := range windows {
+		if err = writeRecord(writer, fileRecord{Operation: fileOperationSaveWindow, Window: &windows[i]}); err != nil {
+			return err
+		}
+	}
+	deliveries, err := memory.GetDeliveries()
+	if err != nil {
+		return err
+	}
+	for i := range deliveries {
+		if err = writeRecord(writer, fileRecord{Operation: fileOperationDelivery, Delivery: &deliveries[i]}); err != nil {
+			return err
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		return fmt.Errorf("unable to write database file %s: %v", file.Name(), err)
+	}
+	if err = file.Sync(); err != nil {
+		return fmt.Errorf("unable to sync database file %s: %v", file.Name(), err)
+	}
+	return nil
+}
+
+func writeRecord(writer io.Writer, record fileRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("unable to encode database record: %v", err)
+	}
+	if _, err = writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("unable to write database record: %v", err)
+	}
+	return nil
+}
+
+// append writes a record to the log file and makes sure it reaches the disk
+// before returning. A record that fails to be written is truncated from the
+// log file, so it does not corrupt the records appended after it
+func (database *FileDatabase) append(record fileRecord) error {
+	if database.file == nil {
+		return fmt.Errorf("database file %s is closed", database.path)
+	}
+	if database.failed != nil {
+		return database.failed
+	}
+	info, err := database.file.Stat()
+	if err != nil {
+		return fmt.Errorf("unable to stat database file: %v", err)
+	}
+	if err = writeRecordFunc(database.file, record); err != nil {
+		if truncateErr := database.file.Truncate(info.Size()); truncateErr != nil {
+			database.failed = fmt.Errorf("database file %s contains a partially written record: %v", database.path, truncateErr)
+		}
+		return err
+	}
+	if err := database.file.Sync(); err != nil {
+		return fmt.Errorf("unable to sync database file: %v", err)
+	}
+	database.appended++
+	return nil
+}
+
+// compactIfNeeded compacts the log file once enough records are appended, so
+// the file does not grow forever while the application keeps running
+func (database *FileDatabase) compactIfNeeded() {
+	if database.appended < fileCompactionMinimumRecords {
+		return
+	}
+	if err := database.compact(); err != nil {
+		log.Printf("unable to compact database file %s: %v", database.path, err)
+	}
+}
+
+// Get retrieve all websites within database, ordered by their ID
+func (database *FileDatabase) Get() ([]Website, error) {
+	return database.memory.Get()
+}
+
+// GetByID retrieve a website based on its ID
+func (database *FileDatabase) GetByID(websiteID string) (Website, error) {
+	return database.memory.GetByID(websiteID)
+}
+
+// GetByHeartbeatToken retrieve the heartbeat website owning the ping token
+func (database *FileDatabase) GetByHeartbeatToken(token string) (Website, error) {
+	return database.memory.GetByHeartbeatToken(token)
+}
+
+// Save store website to the log file and then to memory
+func (database *FileDatabase) Save(web Website) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	if err := database.append(fileRecord{Operation: fileOperationSave, Website: web}); err != nil {
+		return err
+	}
+	defer database.compactIfNeeded()
+	return database.memory.Save(web)
+}
+
+// Update replaces the website by the result of update, the updated website is
+// stored to the log file and then to memory. Writes are serialized so
+// concurrent updates of the website are not lost
+func (database *FileDatabase) Update(websiteID string, update func(Website) Website) (Website, error) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	web, err := database.memory.GetByID(websiteID)
+	if err != nil {
+		return Website{}, err
+	}
+	web = update(web)
+	web.ID = websiteID
+	if err = database.append(fileRecord{Operation: fileOperationSave, Website: web}); err != nil {
+		return Website{}, err
+	}
+	defer database.compactIfNeeded()
+	return web, database.memory.Save(web)
+}
+
+// Delete remove website from database by writing a delete record to the log
+// file
+func (database *FileDatabase) Delete(websiteID string) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	if err := database.append(fileRecord{Operation: fileOperationDelete, Website: Website{ID: websiteID}}); err != nil {
+		return err
+	}
+	defer database.compactIfNeeded()
+	return database.memory.Delete(websiteID)
+}
+
+// SaveCheckResult append a check result to the log file and into the history
+// of the website
+func (database *FileDatabase) SaveCheckResult(result CheckResult) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	if _, err := database.memory.GetByID(result.WebsiteID); err != nil {
+		return err
+	}
+	if err := database.append(fileRecord{Operation: fileOperationResult, Result: &result}); err != nil {
+		return err
+	}
+	defer database.compactIfNeeded()
+	return database.memory.SaveCheckResult(result)
+}
+
+// GetCheckResults retrieve check results of a website within time range
+func (database *FileDatabase) GetCheckResults(websiteID string, from, to time.Time) ([]CheckResult, error) {
+	return database.memory.GetCheckResults(websiteID, from, to)
+}
+
+// GetMaintenanceWindows retrieve all maintenance windows within database
+func (database *FileDatabase) GetMaintenanceWindows() ([]MaintenanceWindow, error) {
+	return database.memory.GetMaintenanceWindows()
+}
+
+// SaveMaintenanceWindow store maintenance window to the log file and then to
+// memory
+func (database *FileDatabase) SaveMaintenanceWindow(window MaintenanceWindow) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	if err := database.append(fileRecord{Operation: fileOperationSaveWindow, Window: &window}); err != nil {
+		return err
+	}
+	defer database.compactIfNeeded()
+	return database.memory.SaveMaintenanceWindow(window)
+}
+
+// DeleteMaintenanceWindow remove maintenance window from database by writing
+// a delete record to the log file
+func (database *FileDatabase) DeleteMaintenanceWindow(windowID string) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	if err := database.append(fileRecord{Operation: fileOperationDeleteWindow, Window: &MaintenanceWindow{ID: windowID}}); err != nil {
+		return err
+	}
+	defer database.compactIfNeeded()
+	return database.memory.DeleteMaintenanceWindow(windowID)
+}
+
+// SaveDelivery append a delivery to the log file and into the delivery log
+func (database *FileDatabase) SaveDelivery(delivery Delivery) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	if err := database.append(fileRecord{Operation: fileOperationDelivery, Delivery: &delivery}); err != nil {
+		return err
+	}
+	defer database.compactIfNeeded()
+	return database.memory.SaveDelivery(delivery)
+}
+
+// GetDeliveries retrieve the delivery log, ordered from the oldest one
+func (database *FileDatabase) GetDeliveries() ([]Delivery, error) {
+	return database.memory.GetDeliveries()
+}
+
+// Close closes the underlying log file
+func (database *FileDatabase) Close() error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	if database.file == nil {
+		return nil
+	}
+	err := database.file.Close()
+	database.file = nil
+	return err
+}
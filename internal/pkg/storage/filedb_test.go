@@ -0,0 +1,433 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestFileDatabase(t *testing.T, path string) *FileDatabase {
+	db, err := NewFileDatabase(path, DefaultHistoryLimit, DefaultHistoryRetention)
+	if err != nil {
+		t.Fatalf("unable to open file database: %v", err)
+	}
+	return db
+}
+
+func TestFileDatabaseGetWebsiteByIDSuccess(t *testing.T) {
+	// arrange
+	db := newTestFileDatabase(t, filepath.Join(t.TempDir(), "gohealthz.db"))
+	defer db.Close()
+	err := db.Save(Website{
+		ID:      "123",
+		URL:     "http://example.com",
+		Healthy: true})
+	if err != nil {
+		t.Errorf("unable to save website to database: %v", err)
+	}
+
+	// action
+	actual, err := db.GetByID("123")
+	if err != nil {
+		t.Errorf("unable to get website by ID: %v", err)
+	}
+
+	// acceptance
+	expected := Website{
+		ID:      "123",
+		URL:     "http://example.com",
+		Healthy: true,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v got %#v", expected, actual)
+	}
+}
+
+func TestFileDatabaseGetWebsiteAllSuccess(t *testing.T) {
+	// arrange
+	db := newTestFileDatabase(t, filepath.Join(t.TempDir(), "gohealthz.db"))
+	defer db.Close()
+	expecteds := map[string]Website{
+		"123": {
+			ID:      "123",
+			URL:     "http://one.example.com",
+			Healthy: true,
+		},
+		"456": {
+			ID:      "456",
+			URL:     "http://two.example.com",
+			Healthy: true,
+		},
+	}
+	for _, website := range expecteds {
+		if err := db.Save(website); err != nil {
+			t.Errorf("unable to save website to database: %v", err)
+		}
+	}
+
+	// action
+	actuals, err := db.Get()
+	if err != nil {
+		t.Errorf("unable to get all websites record from database: %v", err)
+	}
+
+	// acceptance
+	if len(actuals) != len(expecteds) {
+		t.Errorf("expected %d records got %d", len(expecteds), len(actuals))
+	}
+	for _, actual := range actuals {
+		if !reflect.DeepEqual(expecteds[actual.ID], actual) {
+			t.Errorf("expected %#v got %#v", expecteds[actual.ID], actual)
+		}
+	}
+}
+
+func TestFileDatabaseDeleteWebsiteSuccess(t *testing.T) {
+	// arrange
+	db := newTestFileDatabase(t, filepath.Join(t.TempDir(), "gohealthz.db"))
+	defer db.Close()
+	err := db.Save(Website{
+		ID:      "123",
+		URL:     "http://example.com",
+		Healthy: true,
+	})
+	if err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+
+	// action
+	if err = db.Delete("123"); err != nil {
+		t.Errorf("unable to delete website: %v", err)
+	}
+
+	// acceptance
+	_, err = db.GetByID("123")
+	if err != ErrNotFound {
+		t.Errorf("expected error not found, got %v", err)
+	}
+}
+
+func TestFileDatabaseReplayOnReopen(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "gohealthz.db")
+	db := newTestFileDatabase(t, path)
+	if err := db.Save(Website{ID: "123", URL: "http://one.example.com", Healthy: true}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	if err := db.Save(Website{ID: "456", URL: "http://two.example.com"}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	if err := db.Save(Website{ID: "123", URL: "http://one.example.com", Healthy: false}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	if err := db.Delete("456"); err != nil {
+		t.Errorf("unable to delete website: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("unable to close database: %v", err)
+	}
+
+	// action
+	reopened := newTestFileDatabase(t, path)
+	defer reopened.Close()
+
+	// acceptance
+	actual, err := reopened.GetByID("123")
+	if err != nil {
+		t.Errorf("unable to get website by ID: %v", err)
+	}
+	expected := Website{ID: "123", URL: "http://one.example.com", Healthy: false}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v got %#v", expected, actual)
+	}
+	if _, err = reopened.GetByID("456"); err != ErrNotFound {
+		t.Errorf("expected error not found for deleted website, got %v", err)
+	}
+}
+
+func TestFileDatabaseIgnoresTruncatedLastRecord(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "gohealthz.db")
+	db := newTestFileDatabase(t, path)
+	if err := db.Save(Website{ID: "123", URL: "http://example.com", Healthy: true}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("unable to close database: %v", err)
+	}
+	// simulate a crash in the middle of writing a record
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatalf("unable to open database file: %v", err)
+	}
+	if _, err = file.WriteString(`{"op":"save","website":{"ID":"456"`); err != nil {
+		t.Errorf("unable to write database file: %v", err)
+	}
+	file.Close()
+
+	// action
+	reopened := newTestFileDatabase(t, path)
+	defer reopened.Close()
+
+	// acceptance
+	websites, err := reopened.Get()
+	if err != nil {
+		t.Errorf("unable to get websites: %v", err)
+	}
+	if len(websites) != 1 || websites[0].ID != "123" {
+		t.Errorf("expected only website 123 to be restored, got %#v", websites)
+	}
+}
+
+func TestFileDatabaseTruncatesFailedRecord(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "gohealthz.db")
+	db := newTestFileDatabase(t, path)
+	defer func() { writeRecordFunc = writeRecord }()
+	// simulate a write that fails in the middle of a record
+	writeRecordFunc = func(writer io.Writer, record fileRecord) error {
+		if _, err := io.WriteString(writer, `{"op":"save","website":{"ID":"123"`); err != nil {
+			return err
+		}
+		return errors.New("disk full")
+	}
+	if err := db.Save(Website{ID: "123", URL: "http://example.com"}); err == nil {
+		t.Errorf("expected save to fail")
+	}
+	writeRecordFunc = writeRecord
+
+	// action
+	if err := db.Save(Website{ID: "456", URL: "http://example.org"}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("unable to close database: %v", err)
+	}
+
+	// acceptance
+	memory := NewInMemoryDatabase()
+	if err := replayFile(path, memory); err != nil {
+		t.Fatalf("unable to replay database file: %v", err)
+	}
+	websites, err := memory.Get()
+	if err != nil {
+		t.Errorf("unable to get websites: %v", err)
+	}
+	if len(websites) != 1 || websites[0].ID != "456" {
+		t.Errorf("expected only website 456 to be restored, got %#v", websites)
+	}
+}
+
+func TestFileDatabaseReplayCheckResults(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "gohealthz.db")
+	db, err := NewFileDatabase(path, 2, DefaultHistoryRetention)
+	if err != nil {
+		t.Fatalf("unable to open file database: %v", err)
+	}
+	if err = db.Save(Website{ID: "123", URL: "http://example.com"}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	start := time.Date(2019, 3, 8, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		err = db.SaveCheckResult(CheckResult{
+			WebsiteID:  "123",
+			Time:       start.Add(time.Duration(i) * time.Minute),
+			StatusCode: 200 + i,
+		})
+		if err != nil {
+			t.Errorf("unable to save check result: %v", err)
+		}
+	}
+	if err = db.Close(); err != nil {
+		t.Errorf("unable to close database: %v", err)
+	}
+
+	// action
+	reopened, err := NewFileDatabase(path, 2, DefaultHistoryRetention)
+	if err != nil {
+		t.Fatalf("unable to open file database: %v", err)
+	}
+	defer reopened.Close()
+
+	// acceptance
+	actuals, err := reopened.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	if len(actuals) != 2 {
+		t.Fatalf("expected 2 check results, got %d", len(actuals))
+	}
+	if actuals[0].StatusCode != 201 || actuals[1].StatusCode != 202 {
+		t.Errorf("expected the latest check results to be restored, got %#v", actuals)
+	}
+}
+
+func TestFileDatabaseReplayMaintenanceWindows(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "gohealthz.db")
+	db := newTestFileDatabase(t, path)
+	window := MaintenanceWindow{ID: "1", Description: "weekly backup", Tag: "database", Schedule: "0 2 * * sun", Duration: time.Hour}
+	if err := db.SaveMaintenanceWindow(window); err != nil {
+		t.Errorf("unable to save maintenance window: %v", err)
+	}
+	if err := db.SaveMaintenanceWindow(MaintenanceWindow{ID: "2", WebsiteID: "123", Start: time.Now(), End: time.Now().Add(time.Hour)}); err != nil {
+		t.Errorf("unable to save maintenance window: %v", err)
+	}
+	if err := db.DeleteMaintenanceWindow("2"); err != nil {
+		t.Errorf("unable to delete maintenance window: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("unable to close database: %v", err)
+	}
+
+	// action
+	reopened := newTestFileDatabase(t, path)
+	defer reopened.Close()
+
+	// acceptance
+	actuals, err := reopened.GetMaintenanceWindows()
+	if err != nil {
+		t.Errorf("unable to get maintenance windows: %v", err)
+	}
+	if !reflect.DeepEqual(actuals, []MaintenanceWindow{window}) {
+		t.Errorf("expected %#v got %#v", []MaintenanceWindow{window}, actuals)
+	}
+}
+
+func TestFileDatabaseReplayDeliveries(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "gohealthz.db")
+	db := newTestFileDatabase(t, path)
+	delivery := Delivery{
+		Notification: Notification{
+			WebsiteID: "123",
+			URL:       "https://example.com",
+			OldState:  StateUp,
+			NewState:  StateDown,
+			LastError: "unexpected status code 503",
+			Time:      time.Date(2019, 3, 8, 12, 0, 0, 0, time.UTC),
+		},
+		Webhook:    "https://hooks.example.com",
+		Time:       time.Date(2019, 3, 8, 12, 0, 1, 0, time.UTC),
+		Attempts:   3,
+		StatusCode: 502,
+		Error:      "unexpected status code 502",
+	}
+	if err := db.SaveDelivery(delivery); err != nil {
+		t.Errorf("unable to save delivery: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("unable to close database: %v", err)
+	}
+
+	// action
+	reopened := newTestFileDatabase(t, path)
+	defer reopened.Close()
+
+	// acceptance
+	actuals, err := reopened.GetDeliveries()
+	if err != nil {
+		t.Errorf("unable to get deliveries: %v", err)
+	}
+	if !reflect.DeepEqual(actuals, []Delivery{delivery}) {
+		t.Errorf("expected %#v got %#v", []Delivery{delivery}, actuals)
+	}
+}
+
+func TestFileDatabaseCompaction(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "gohealthz.db")
+	db := newTestFileDatabase(t, path)
+	for _, url := range []string{"http://example.com", "http://example.org", "http://example.net"} {
+		if err := db.Save(Website{ID: "123", URL: url}); err != nil {
+			t.Errorf("unable to save website: %v", err)
+		}
+	}
+	if err := db.Save(Website{ID: "456", URL: "http://example.com"}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	if err := db.Delete("456"); err != nil {
+		t.Errorf("unable to delete website: %v", err)
+	}
+
+	// action
+	db.appended = fileCompactionMinimumRecords
+	if err := db.Save(Website{ID: "789", URL: "http://example.com"}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	if err := db.Save(Website{ID: "123", URL: "http://example.io"}); err != nil {
+		t.Errorf("unable to save website after compaction: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("unable to close database: %v", err)
+	}
+
+	// acceptance
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read database file: %v", err)
+	}
+	// snapshot of 2 websites followed by the record appended after compaction
+	if lines := bytes.Count(content, []byte("\n")); lines != 3 {
+		t.Errorf("expected 3 records in compacted database file, got %d", lines)
+	}
+	reopened := newTestFileDatabase(t, path)
+	defer reopened.Close()
+	website, err := reopened.GetByID("123")
+	if err != nil || website.URL != "http://example.io" {
+		t.Errorf("expected website 123 with URL http://example.io, got %#v: %v", website, err)
+	}
+	if _, err = reopened.GetByID("456"); err != ErrNotFound {
+		t.Errorf("expected deleted website to stay deleted, got %v", err)
+	}
+	if _, err = reopened.GetByID("789"); err != nil {
+		t.Errorf("unable to get website 789: %v", err)
+	}
+}
+
+func TestFileDatabaseStaysWritableWhenCompactionFails(t *testing.T) {
+	// arrange
+	path := filepath.Join(t.TempDir(), "gohealthz.db")
+	db := newTestFileDatabase(t, path)
+	// temporary file of compaction can not be created over a directory
+	if err := os.Mkdir(path+".tmp", 0700); err != nil {
+		t.Fatalf("unable to create directory: %v", err)
+	}
+
+	// action
+	db.appended = fileCompactionMinimumRecords
+	if err := db.Save(Website{ID: "123", URL: "http://example.com"}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	if err := db.compact(); err == nil {
+		t.Errorf("expected compaction to fail")
+	}
+	err := db.Save(Website{ID: "456", URL: "http://example.org"})
+
+	// acceptance
+	if err != nil {
+		t.Errorf("expected database to stay writable after failed compaction, got %v", err)
+	}
+	if err = db.Close(); err != nil {
+		t.Errorf("unable to close database: %v", err)
+	}
+	if err = os.Remove(path + ".tmp"); err != nil {
+		t.Fatalf("unable to remove directory: %v", err)
+	}
+	reopened := newTestFileDatabase(t, path)
+	defer reopened.Close()
+	websites, err := reopened.Get()
+	if err != nil {
+		t.Errorf("unable to get websites: %v", err)
+	}
+	if len(websites) != 2 {
+		t.Errorf("expected 2 websites to be restored, got %#v", websites)
+	}
+}
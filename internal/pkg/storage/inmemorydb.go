@@ -1,51 +1,282 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
 
 var (
 	// ErrNotFound an error (string) indicates that the records is not found
 	ErrNotFound = errors.New("not found")
 )
 
-// InMemoryDatabase storage within memory
+// InMemoryDatabase storage within memory. It is safe for concurrent use by
+// multiple goroutines, and every read returns copies of the records so the
+// caller can freely modify them without affecting the database
 type InMemoryDatabase struct {
-	webs map[string]Website
+	mutex        sync.RWMutex
+	webs         map[string]Website
+	histories    map[string][]CheckResult
+	windows      map[string]MaintenanceWindow
+	deliveries   []Delivery
+	historyLimit int
+	// tokens IDs of heartbeat websites by their ping token
+	tokens map[string]string
+	// historyRetention how long check results and deliveries are kept,
+	// counted back from the latest one
+	historyRetention time.Duration
 }
 
 // NewInMemoryDatabase creates instances for database sotored within memeory
 func NewInMemoryDatabase() *InMemoryDatabase {
 	return &InMemoryDatabase{
-		webs: make(map[string]Website, 0),
+		webs:             make(map[string]Website, 0),
+		tokens:           make(map[string]string, 0),
+		histories:        make(map[string][]CheckResult, 0),
+		windows:          make(map[string]MaintenanceWindow, 0),
+		historyLimit:     DefaultHistoryLimit,
+		historyRetention: DefaultHistoryRetention,
+	}
+}
+
+// SetHistoryLimit sets maximum number of check results kept for every
+// website. Zero or negative limit means the history is unlimited
+func (database *InMemoryDatabase) SetHistoryLimit(limit int) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	database.historyLimit = limit
+	database.trim()
+}
+
+// SetHistoryRetention sets how long check results (and deliveries) are kept,
+// counted back from the latest one rather than from now so a history that is
+// not updated anymore is kept as it is. Zero or negative retention means the
+// history is kept forever
+func (database *InMemoryDatabase) SetHistoryRetention(retention time.Duration) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	database.historyRetention = retention
+	database.trim()
+}
+
+func (database *InMemoryDatabase) trim() {
+	for websiteID, history := range database.histories {
+		database.histories[websiteID] = trimHistory(history, database.historyLimit, database.historyRetention)
 	}
+	database.deliveries = trimDeliveries(database.deliveries, database.historyLimit, database.historyRetention)
 }
 
-// Get retrieve all websites within database
-func (database InMemoryDatabase) Get() ([]Website, error) {
+// Get retrieve all websites within database, ordered by their ID
+func (database *InMemoryDatabase) Get() ([]Website, error) {
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
 	var websites []Website
 	for _, web := range database.webs {
-		websites = append(websites, web)
+		websites = append(websites, web.clone())
 	}
+	sort.Slice(websites, func(i, j int) bool { return websites[i].ID < websites[j].ID })
 	return websites, nil
 }
 
 // GetByID retrieve a website based on its ID
-func (database InMemoryDatabase) GetByID(websiteID string) (Website, error) {
-	for _, web := range database.webs {
-		if web.ID == websiteID {
-			return web, nil
-		}
+func (database *InMemoryDatabase) GetByID(websiteID string) (Website, error) {
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+	web, ok := database.webs[websiteID]
+	if !ok {
+		return Website{}, ErrNotFound
 	}
-	return Website{}, ErrNotFound
+	return web.clone(), nil
+}
+
+// GetByHeartbeatToken retrieve the heartbeat website owning the ping token
+func (database *InMemoryDatabase) GetByHeartbeatToken(token string) (Website, error) {
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+	websiteID, ok := database.tokens[token]
+	if !ok {
+		return Website{}, ErrNotFound
+	}
+	return database.webs[websiteID].clone(), nil
 }
 
 // Save store URL to in-memory database
 func (database *InMemoryDatabase) Save(web Website) error {
-	database.webs[web.ID] = web
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	database.put(web)
 	return nil
 }
 
+// put stores a copy of the website and keeps the token index up to date
+func (database *InMemoryDatabase) put(web Website) {
+	if previous, ok := database.webs[web.ID]; ok {
+		delete(database.tokens, previous.Heartbeat.Token)
+	}
+	if web.CheckType == CheckHeartbeat && web.Heartbeat.Token != "" {
+		database.tokens[web.Heartbeat.Token] = web.ID
+	}
+	database.webs[web.ID] = web.clone()
+}
+
+// Update replaces the website by the result of update while holding the
+// lock, so concurrent updates of the website are not lost
+func (database *InMemoryDatabase) Update(websiteID string, update func(Website) Website) (Website, error) {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	web, ok := database.webs[websiteID]
+	if !ok {
+		return Website{}, ErrNotFound
+	}
+	web = update(web.clone())
+	web.ID = websiteID
+	database.put(web)
+	return web, nil
+}
+
 // Delete remove website URL from database
 func (database *InMemoryDatabase) Delete(websiteID string) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	if web, ok := database.webs[websiteID]; ok {
+		delete(database.tokens, web.Heartbeat.Token)
+	}
 	delete(database.webs, websiteID)
+	delete(database.histories, websiteID)
+	for windowID, window := range database.windows {
+		if window.WebsiteID == websiteID {
+			delete(database.windows, windowID)
+		}
+	}
 	return nil
 }
+
+// SaveCheckResult append a check result into the history of the website.
+// ErrNotFound is returned when the website does not exist (anymore)
+func (database *InMemoryDatabase) SaveCheckResult(result CheckResult) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	if _, ok := database.webs[result.WebsiteID]; !ok {
+		return ErrNotFound
+	}
+	history := database.histories[result.WebsiteID]
+	// results are almost always saved in chronological order, but concurrent
+	// checks may finish slightly out of order
+	index := sort.Search(len(history), func(i int) bool {
+		return history[i].Time.After(result.Time)
+	})
+	history = append(history, CheckResult{})
+	copy(history[index+1:], history[index:])
+	history[index] = result.clone()
+	database.histories[result.WebsiteID] = trimHistory(history, database.historyLimit, database.historyRetention)
+	return nil
+}
+
+// GetCheckResults retrieve check results of a website within time range
+func (database *InMemoryDatabase) GetCheckResults(websiteID string, from, to time.Time) ([]CheckResult, error) {
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+	// initilize with make with 0 capacity so the result is never nil
+	results := make([]CheckResult, 0)
+	for _, result := range database.histories[websiteID] {
+		if !from.IsZero() && result.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && result.Time.After(to) {
+			continue
+		}
+		results = append(results, result.clone())
+	}
+	return results, nil
+}
+
+// GetMaintenanceWindows retrieve all maintenance windows within database,
+// ordered by their ID
+func (database *InMemoryDatabase) GetMaintenanceWindows() ([]MaintenanceWindow, error) {
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+	// initilize with make with 0 capacity so the result is never nil
+	windows := make([]MaintenanceWindow, 0, len(database.windows))
+	for _, window := range database.windows {
+		windows = append(windows, window)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].ID < windows[j].ID })
+	return windows, nil
+}
+
+// SaveMaintenanceWindow store maintenance window to in-memory database
+func (database *InMemoryDatabase) SaveMaintenanceWindow(window MaintenanceWindow) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	database.windows[window.ID] = window
+	return nil
+}
+
+// DeleteMaintenanceWindow remove maintenance window from database
+func (database *InMemoryDatabase) DeleteMaintenanceWindow(windowID string) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	delete(database.windows, windowID)
+	return nil
+}
+
+// SaveDelivery append a delivery into the delivery log
+func (database *InMemoryDatabase) SaveDelivery(delivery Delivery) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	database.deliveries = trimDeliveries(append(database.deliveries, delivery), database.historyLimit, database.historyRetention)
+	return nil
+}
+
+// GetDeliveries retrieve the delivery log, ordered from the oldest one
+func (database *InMemoryDatabase) GetDeliveries() ([]Delivery, error) {
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+	// initilize with make with 0 capacity so the result is never nil
+	deliveries := make([]Delivery, 0, len(database.deliveries))
+	return append(deliveries, database.deliveries...), nil
+}
+
+// trimDeliveries discards the oldest deliveries so the log contains at most
+// limit deliveries, none of them older than retention before the latest one
+func trimDeliveries(deliveries []Delivery, limit int, retention time.Duration) []Delivery {
+	first := 0
+	if retention > 0 && len(deliveries) > 0 {
+		oldest := deliveries[len(deliveries)-1].Time.Add(-retention)
+		for first < len(deliveries) && deliveries[first].Time.Before(oldest) {
+			first++
+		}
+	}
+	if limit > 0 && len(deliveries)-first > limit {
+		first = len(deliveries) - limit
+	}
+	if first == 0 {
+		return deliveries
+	}
+	trimmed := make([]Delivery, len(deliveries)-first)
+	copy(trimmed, deliveries[first:])
+	return trimmed
+}
+
+// trimHistory discards the oldest results so the history contains at most
+// limit results, none of them older than retention before the latest one
+func trimHistory(history []CheckResult, limit int, retention time.Duration) []CheckResult {
+	first := 0
+	if retention > 0 && len(history) > 0 {
+		oldest := history[len(history)-1].Time.Add(-retention)
+		first = sort.Search(len(history), func(i int) bool {
+			return !history[i].Time.Before(oldest)
+		})
+	}
+	if limit > 0 && len(history)-first > limit {
+		first = len(history) - limit
+	}
+	if first == 0 {
+		return history
+	}
+	trimmed := make([]CheckResult, len(history)-first)
+	copy(trimmed, history[first:])
+	return trimmed
+}
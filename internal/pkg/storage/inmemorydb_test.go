@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGetWebsiteByIDSuccess(t *testing.T) {
@@ -27,7 +29,7 @@ func TestGetWebsiteByIDSuccess(t *testing.T) {
 		URL:     "http://example.com",
 		Healthy: true,
 	}
-	if actual != expected {
+	if !reflect.DeepEqual(actual, expected) {
 		t.Errorf("expected %#v got %#v", expected, actual)
 	}
 }
@@ -71,7 +73,7 @@ func TestGetWebsiteAllSuccess(t *testing.T) {
 		},
 	}
 	for index, expected := range expecteds {
-		if expected != actuals[index] {
+		if !reflect.DeepEqual(expected, actuals[index]) {
 			t.Errorf("expected %#v got %#v", expected, actuals)
 		}
 	}
@@ -101,7 +103,7 @@ func TestSaveWebsiteToDatabaseSuccess(t *testing.T) {
 		URL:     "https://www.example.com",
 		Healthy: true,
 	}
-	if actual != expected {
+	if !reflect.DeepEqual(actual, expected) {
 		t.Errorf("expected %#v got %#v", expected, actual)
 	}
 }
@@ -129,3 +131,319 @@ func TestDeleteWebsiteFromDatabaseSuccess(t *testing.T) {
 		t.Errorf("expected error not found, got %v", err)
 	}
 }
+
+func TestGetWebsiteByHeartbeatToken(t *testing.T) {
+	// arrange
+	db := NewInMemoryDatabase()
+	web := Website{ID: "123", URL: "nightly backup", CheckType: CheckHeartbeat, Heartbeat: Heartbeat{Token: "t0ken"}}
+	if err := db.Save(web); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	if err := db.Save(Website{ID: "456", URL: "http://example.com", Heartbeat: Heartbeat{Token: "http"}}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+
+	// action
+	actual, err := db.GetByHeartbeatToken("t0ken")
+	_, errHTTP := db.GetByHeartbeatToken("http")
+	if _, err := db.Update("123", func(web Website) Website {
+		web.Heartbeat.Token = "r0tated"
+		return web
+	}); err != nil {
+		t.Errorf("unable to update website: %v", err)
+	}
+	_, errRotated := db.GetByHeartbeatToken("t0ken")
+	if err := db.Delete("123"); err != nil {
+		t.Errorf("unable to delete website: %v", err)
+	}
+	_, errDeleted := db.GetByHeartbeatToken("r0tated")
+
+	// acceptance
+	if err != nil || !reflect.DeepEqual(actual, web) {
+		t.Errorf("expected %#v got %#v: %v", web, actual, err)
+	}
+	if errHTTP != ErrNotFound {
+		t.Errorf("expected error not found for website that is not a heartbeat, got %v", errHTTP)
+	}
+	if errRotated != ErrNotFound {
+		t.Errorf("expected error not found for replaced token, got %v", errRotated)
+	}
+	if errDeleted != ErrNotFound {
+		t.Errorf("expected error not found for deleted website, got %v", errDeleted)
+	}
+}
+
+func TestGetCheckResultsWithinTimeRange(t *testing.T) {
+	// arrange
+	db := NewInMemoryDatabase()
+	if err := db.Save(Website{ID: "123", URL: "http://example.com"}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	start := time.Date(2019, 3, 8, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		err := db.SaveCheckResult(CheckResult{
+			WebsiteID:  "123",
+			Time:       start.Add(time.Duration(i) * time.Minute),
+			Healthy:    true,
+			StatusCode: 200,
+		})
+		if err != nil {
+			t.Errorf("unable to save check result: %v", err)
+		}
+	}
+
+	// action
+	actuals, err := db.GetCheckResults("123", start.Add(time.Minute), start.Add(3*time.Minute))
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+
+	// acceptance
+	if len(actuals) != 3 {
+		t.Fatalf("expected 3 check results, got %d", len(actuals))
+	}
+	for index, actual := range actuals {
+		expected := start.Add(time.Duration(index+1) * time.Minute)
+		if !actual.Time.Equal(expected) {
+			t.Errorf("expected check result at %s, got %s", expected, actual.Time)
+		}
+	}
+}
+
+func TestSaveCheckResultKeepsHistoryLimit(t *testing.T) {
+	// arrange
+	db := NewInMemoryDatabase()
+	db.SetHistoryLimit(3)
+	if err := db.Save(Website{ID: "123", URL: "http://example.com"}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	start := time.Date(2019, 3, 8, 10, 0, 0, 0, time.UTC)
+
+	// action
+	for i := 0; i < 5; i++ {
+		err := db.SaveCheckResult(CheckResult{
+			WebsiteID: "123",
+			Time:      start.Add(time.Duration(i) * time.Minute),
+		})
+		if err != nil {
+			t.Errorf("unable to save check result: %v", err)
+		}
+	}
+
+	// acceptance
+	actuals, err := db.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	if len(actuals) != 3 {
+		t.Fatalf("expected 3 check results, got %d", len(actuals))
+	}
+	if !actuals[0].Time.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("expected oldest results to be discarded, got first result at %s", actuals[0].Time)
+	}
+}
+
+func TestSaveCheckResultKeepsHistoryRetention(t *testing.T) {
+	// arrange
+	db := NewInMemoryDatabase()
+	if err := db.Save(Website{ID: "123", URL: "http://example.com"}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	start := time.Date(2019, 3, 8, 10, 0, 0, 0, time.UTC)
+
+	// action
+	// a check result every 5 minutes over 31 days, more than the previous
+	// count based limit of 1000 results
+	for i := 0; i <= 31*24*12; i++ {
+		err := db.SaveCheckResult(CheckResult{
+			WebsiteID: "123",
+			Time:      start.Add(time.Duration(i) * 5 * time.Minute),
+		})
+		if err != nil {
+			t.Errorf("unable to save check result: %v", err)
+		}
+	}
+
+	// acceptance
+	actuals, err := db.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	latest := start.Add(31 * 24 * time.Hour)
+	if len(actuals) != 30*24*12+1 {
+		t.Fatalf("expected check results of the last 30 days, got %d", len(actuals))
+	}
+	if !actuals[0].Time.Equal(latest.Add(-DefaultHistoryRetention)) || !actuals[len(actuals)-1].Time.Equal(latest) {
+		t.Errorf("expected check results from %s to %s, got from %s to %s",
+			latest.Add(-DefaultHistoryRetention), latest, actuals[0].Time, actuals[len(actuals)-1].Time)
+	}
+}
+
+func TestSaveCheckResultOfUnknownWebsite(t *testing.T) {
+	// arrange
+	db := NewInMemoryDatabase()
+
+	// action
+	err := db.SaveCheckResult(CheckResult{WebsiteID: "123", Time: time.Now()})
+
+	// acceptance
+	if err != ErrNotFound {
+		t.Errorf("expected error not found, got %v", err)
+	}
+}
+
+func TestCheckResultsDoNotShareMemory(t *testing.T) {
+	// arrange
+	db := NewInMemoryDatabase()
+	if err := db.Save(Website{ID: "123", URL: "https://example.com"}); err != nil {
+		t.Fatalf("unable to save website: %v", err)
+	}
+	result := CheckResult{
+		WebsiteID:   "123",
+		Time:        time.Now(),
+		Certificate: &Certificate{Issuer: "CA", DNSNames: []string{"example.com"}},
+		Redirects:   []string{"https://www.example.com"},
+	}
+	if err := db.SaveCheckResult(result); err != nil {
+		t.Fatalf("unable to save check result: %v", err)
+	}
+
+	// action
+	result.Certificate.DNSNames[0] = "saved.example.com"
+	result.Redirects[0] = "https://saved.example.com"
+	results, err := db.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected 1 check result, got %d: %v", len(results), err)
+	}
+	results[0].Certificate.Issuer = "another CA"
+	results[0].Certificate.DNSNames[0] = "read.example.com"
+	results[0].Redirects[0] = "https://read.example.com"
+
+	// acceptance
+	results, _ = db.GetCheckResults("123", time.Time{}, time.Time{})
+	certificate := results[0].Certificate
+	if certificate.Issuer != "CA" || certificate.DNSNames[0] != "example.com" || results[0].Redirects[0] != "https://www.example.com" {
+		t.Errorf("expected stored check result to be unchanged, got %+v %+v", certificate, results[0].Redirects)
+	}
+}
+
+func TestDeleteWebsiteRemovesCheckResults(t *testing.T) {
+	// arrange
+	db := NewInMemoryDatabase()
+	if err := db.Save(Website{ID: "123", URL: "http://example.com"}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	if err := db.SaveCheckResult(CheckResult{WebsiteID: "123", Time: time.Now()}); err != nil {
+		t.Errorf("unable to save check result: %v", err)
+	}
+
+	// action
+	if err := db.Delete("123"); err != nil {
+		t.Errorf("unable to delete website: %v", err)
+	}
+
+	// acceptance
+	actuals, err := db.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	if len(actuals) != 0 {
+		t.Errorf("expected no check results after delete, got %d", len(actuals))
+	}
+}
+
+func TestDeleteWebsiteRemovesMaintenanceWindows(t *testing.T) {
+	// arrange
+	db := NewInMemoryDatabase()
+	if err := db.Save(Website{ID: "123", URL: "http://example.com", Tags: []string{"database"}}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	tagWindow := MaintenanceWindow{ID: "1", Tag: "database", Schedule: "0 2 * * sun", Duration: time.Hour}
+	windows := []MaintenanceWindow{
+		tagWindow,
+		{ID: "2", WebsiteID: "123", Start: time.Date(2019, 3, 8, 22, 0, 0, 0, time.UTC), End: time.Date(2019, 3, 8, 23, 0, 0, 0, time.UTC)},
+	}
+	for _, window := range windows {
+		if err := db.SaveMaintenanceWindow(window); err != nil {
+			t.Errorf("unable to save maintenance window: %v", err)
+		}
+	}
+
+	// action
+	if err := db.Delete("123"); err != nil {
+		t.Errorf("unable to delete website: %v", err)
+	}
+
+	// acceptance
+	actuals, err := db.GetMaintenanceWindows()
+	if err != nil {
+		t.Errorf("unable to get maintenance windows: %v", err)
+	}
+	if !reflect.DeepEqual(actuals, []MaintenanceWindow{tagWindow}) {
+		t.Errorf("expected %#v got %#v", []MaintenanceWindow{tagWindow}, actuals)
+	}
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	// arrange
+	db := NewInMemoryDatabase()
+	windows := []MaintenanceWindow{
+		{ID: "2", Tag: "database", Schedule: "0 2 * * sun", Duration: time.Hour},
+		{ID: "1", WebsiteID: "123", Start: time.Date(2019, 3, 8, 22, 0, 0, 0, time.UTC), End: time.Date(2019, 3, 8, 23, 0, 0, 0, time.UTC)},
+		{ID: "3", Tag: "cache", Schedule: "0 3 * * *", Duration: time.Hour},
+	}
+	for _, window := range windows {
+		if err := db.SaveMaintenanceWindow(window); err != nil {
+			t.Errorf("unable to save maintenance window: %v", err)
+		}
+	}
+
+	// action
+	if err := db.DeleteMaintenanceWindow("3"); err != nil {
+		t.Errorf("unable to delete maintenance window: %v", err)
+	}
+	actuals, err := db.GetMaintenanceWindows()
+
+	// acceptance
+	if err != nil {
+		t.Errorf("unable to get maintenance windows: %v", err)
+	}
+	expected := []MaintenanceWindow{windows[1], windows[0]}
+	if !reflect.DeepEqual(actuals, expected) {
+		t.Errorf("expected %#v got %#v", expected, actuals)
+	}
+}
+
+func TestDeliveriesAreTrimmedToHistoryLimit(t *testing.T) {
+	// arrange
+	db := NewInMemoryDatabase()
+	db.SetHistoryLimit(2)
+	start := time.Date(2019, 3, 8, 12, 0, 0, 0, time.UTC)
+	var deliveries []Delivery
+	for i := 0; i < 3; i++ {
+		deliveries = append(deliveries, Delivery{
+			Notification: Notification{WebsiteID: "123", OldState: StateUp, NewState: StateDown, Time: start.Add(time.Duration(i) * time.Minute)},
+			Webhook:      "https://hooks.example.com",
+			Time:         start.Add(time.Duration(i) * time.Minute),
+			Attempts:     1,
+			StatusCode:   200,
+			Delivered:    true,
+		})
+	}
+
+	// action
+	for _, delivery := range deliveries {
+		if err := db.SaveDelivery(delivery); err != nil {
+			t.Errorf("unable to save delivery: %v", err)
+		}
+	}
+	actuals, err := db.GetDeliveries()
+
+	// acceptance
+	if err != nil {
+		t.Errorf("unable to get deliveries: %v", err)
+	}
+	if !reflect.DeepEqual(actuals, deliveries[1:]) {
+		t.Errorf("expected %#v got %#v", deliveries[1:], actuals)
+	}
+}
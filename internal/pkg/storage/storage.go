@@ -1,20 +1,473 @@
 package storage
 
+import (
+	"net/url"
+	"time"
+)
+
+const (
+	// DefaultHistoryLimit maximum number of check results kept for every
+	// website unless configured otherwise. Zero means the number is not
+	// limited, so the history is only limited by its retention
+	DefaultHistoryLimit = 0
+	// DefaultHistoryRetention how long check results are kept unless
+	// configured otherwise, long enough for the longest uptime window (30
+	// days)
+	DefaultHistoryRetention = 30 * 24 * time.Hour
+)
+
 // Database interface to do database operations
 type Database interface {
-	// Get retrieve all stored websites within database
+	// Get retrieve all stored websites within database, ordered by their ID
 	Get() ([]Website, error)
 	// GetByID retrieve a website based on its ID
 	GetByID(websiteID string) (Website, error)
+	// GetByHeartbeatToken retrieve the heartbeat website owning the ping
+	// token. ErrNotFound is returned when there is no such website
+	GetByHeartbeatToken(token string) (Website, error)
 	// Save store new website URL into database
 	Save(web Website) error
-	// Delete remove URL from database based on its ID
+	// Update atomically replaces the website with the given ID by the result
+	// of update, and returns the stored website. ErrNotFound is returned when
+	// there is no such website
+	Update(websiteID string, update func(Website) Website) (Website, error)
+	// Delete remove URL from database based on its ID, including its check
+	// history and its maintenance windows. Windows applied by tag are kept
 	Delete(websiteID string) error
+	// SaveCheckResult append a check result into the history of the website.
+	// The oldest results are discarded once the history exceeds its limit or
+	// retention
+	SaveCheckResult(result CheckResult) error
+	// GetCheckResults retrieve check results of a website that happened
+	// within time range from and to (inclusive), ordered from the oldest one.
+	// Zero value of from or to means the range is unbounded on that side
+	GetCheckResults(websiteID string, from, to time.Time) ([]CheckResult, error)
+	// GetMaintenanceWindows retrieve all stored maintenance windows
+	GetMaintenanceWindows() ([]MaintenanceWindow, error)
+	// SaveMaintenanceWindow store (or replace) a maintenance window
+	SaveMaintenanceWindow(window MaintenanceWindow) error
+	// DeleteMaintenanceWindow remove a maintenance window based on its ID
+	DeleteMaintenanceWindow(windowID string) error
+	// SaveDelivery append a notification delivery into the delivery log. The
+	// oldest deliveries are discarded once the log exceeds history limit or
+	// retention
+	SaveDelivery(delivery Delivery) error
+	// GetDeliveries retrieve the delivery log, ordered from the oldest one
+	GetDeliveries() ([]Delivery, error)
 }
 
+// State health state of a website
+type State string
+
+const (
+	// StateUnknown the website is not checked yet
+	StateUnknown State = "unknown"
+	// StateUp the website is healthy
+	StateUp State = "up"
+	// StateDegraded the website is healthy but responds slower than its
+	// degraded latency threshold
+	StateDegraded State = "degraded"
+	// StateDown the website is not healthy
+	StateDown State = "down"
+)
+
+// CheckType type of probe used to check a website
+type CheckType string
+
+const (
+	// CheckHTTP sends HTTP request to URL of the website and evaluates the
+	// response against its assertions
+	CheckHTTP CheckType = "http"
+	// CheckTCP opens TCP connection to address (host:port) of the website,
+	// optionally exchanging banner bytes
+	CheckTCP CheckType = "tcp"
+	// CheckTLS opens TLS connection to address (host:port) of the website
+	// and validates its certificate
+	CheckTLS CheckType = "tls"
+	// CheckDNS resolves records of domain name of the website and compares
+	// them against expected values
+	CheckDNS CheckType = "dns"
+	// CheckGRPC calls Check of gRPC Health Checking Protocol
+	// (grpc.health.v1.Health) of the website
+	CheckGRPC CheckType = "grpc"
+	// CheckWebSocket performs WebSocket handshake with the website, optionally
+	// exchanging a message
+	CheckWebSocket CheckType = "websocket"
+	// CheckHeartbeat waits for pings of the website (e.g. a cron job) instead
+	// of probing it, the website is down when no ping arrives in time
+	CheckHeartbeat CheckType = "heartbeat"
+)
+
 // Website models that holds URL address of the website
 type Website struct {
 	ID      string
 	URL     string
 	Healthy bool
+	State   State
+	// Tags labels of the website, e.g. to apply maintenance windows to a
+	// group of websites
+	Tags []string
+	// CheckType type of probe used to check the website. Empty means HTTP
+	CheckType CheckType
+	// Interval duration between two checks of the website. Zero means the
+	// default interval of the updater is used
+	Interval time.Duration
+	// Schedule cron expression (e.g. "*/15 9-17 * * mon-fri") of when the
+	// website is checked, used instead of interval when it is not empty
+	Schedule string
+	// Timeout maximum duration of a check of the website. Zero means the
+	// default timeout of the updater is used
+	Timeout time.Duration
+	// DegradedLatency the website is considered degraded when a check takes
+	// longer than this threshold. Zero means the website is never degraded
+	DegradedLatency time.Duration
+	// Request method, headers, body and credentials of the request sent by
+	// HTTP check
+	Request HTTPRequest
+	// Redirects how HTTP check follows redirects of the website
+	Redirects RedirectPolicy
+	// Assertions conditions the response must meet for the website to be
+	// healthy
+	Assertions Assertions
+	// TCP options of TCP check
+	TCP TCPOptions
+	// DNS options of DNS check
+	DNS DNSOptions
+	// GRPC options of gRPC check
+	GRPC GRPCOptions
+	// WebSocket options of WebSocket check
+	WebSocket WebSocketOptions
+	// Heartbeat options and pings of heartbeat check
+	Heartbeat Heartbeat
+	// CertificateExpiryDays the website is considered degraded when its
+	// certificate expires within this number of days. Zero means the default
+	// number of days is used
+	CertificateExpiryDays int
+	// Certificate the certificate presented by the website on its latest
+	// check, nil when the website does not use TLS
+	Certificate *Certificate
+	// FailureThreshold number of consecutive failed checks before the
+	// website goes down. Zero means the default threshold is used
+	FailureThreshold int
+	// SuccessThreshold number of consecutive successful checks before the
+	// website goes up again. Zero means the default threshold is used
+	SuccessThreshold int
+	// ConsecutiveFailures number of failed checks in a row so far
+	ConsecutiveFailures int
+	// ConsecutiveSuccesses number of successful checks in a row so far
+	ConsecutiveSuccesses int
+}
+
+// Assertions conditions a HTTP response must meet for the website to be
+// healthy. Zero value only allows status code 200
+type Assertions struct {
+	// StatusCodes allowed ranges of status code. Empty means only 200 is
+	// allowed
+	StatusCodes []StatusCodeRange
+	// BodyContains substrings the body must contain
+	BodyContains []string
+	// BodyNotContains substrings the body must not contain
+	BodyNotContains []string
+	// BodyMatches regular expressions the body must match
+	BodyMatches []string
+	// BodyNotMatches regular expressions the body must not match
+	BodyNotMatches []string
+	// Headers required headers of the response by their name. Empty value
+	// means the header only needs to be present
+	Headers map[string]string
+	// MaxBodySize maximum size of the body in bytes. Zero means unlimited
+	MaxBodySize int64
+	// JSON JSONPath-style assertions on JSON body, e.g. `$.status == "ok"`
+	// or `$.checks[*].healthy == true`
+	JSON []string
+}
+
+// HTTPRequest options of the request sent by HTTP check. Zero value sends GET
+// request without body
+type HTTPRequest struct {
+	// Method HTTP method of the request. Empty means GET
+	Method string
+	// Headers additional headers of the request by their name. Host header
+	// overrides host of the request
+	Headers map[string]string
+	// Body body of the request
+	Body string
+	// Auth credentials of the request
+	Auth Auth
+}
+
+// Auth credentials of a HTTP request, either basic authentication or bearer
+// token
+type Auth struct {
+	// Username and Password credentials of basic authentication
+	Username string
+	Password string
+	// BearerToken token sent as bearer in Authorization header
+	BearerToken string
+}
+
+// RedirectPolicy how HTTP check follows redirects. Zero value follows up to
+// DefaultMaxRedirects redirects to any URL
+type RedirectPolicy struct {
+	// NoFollow the redirect response itself is evaluated against the
+	// assertions instead of being followed
+	NoFollow bool
+	// MaxHops maximum number of followed redirects, the check fails once the
+	// website redirects more. Zero means DefaultMaxRedirects
+	MaxHops int
+	// FinalURL URL the final response must come from. Empty means any URL
+	FinalURL string
+}
+
+// DefaultMaxRedirects maximum number of followed redirects unless configured
+// otherwise, the same limit of http.Client
+const DefaultMaxRedirects = 10
+
+// TCPOptions options of TCP check
+type TCPOptions struct {
+	// Send bytes written to the connection once it is established
+	Send string
+	// Expect bytes that must be received from the connection (e.g. a
+	// banner). Empty means an established connection is enough
+	Expect string
+}
+
+// DNSOptions options of DNS check
+type DNSOptions struct {
+	// RecordType type of resolved records: A, AAAA, CNAME, TXT or MX. Empty
+	// means A
+	RecordType string
+	// Resolver address (host:port) of DNS server to query. Empty means the
+	// resolver of the system is used
+	Resolver string
+	// Expected values that must be resolved, every one of them must be
+	// within resolved records. Empty means any record is enough
+	Expected []string
+}
+
+// GRPCOptions options of gRPC check
+type GRPCOptions struct {
+	// Service name of the checked service. Empty means health of the whole
+	// server
+	Service string
+}
+
+// WebSocketOptions options of WebSocket check
+type WebSocketOptions struct {
+	// Send text message sent once the handshake is done
+	Send string
+	// Expect substring the first reply must contain. When only Send is set
+	// any reply is enough
+	Expect string
+}
+
+// Heartbeat options and pings of heartbeat check. Interval of the website is
+// the expected period between two pings
+type Heartbeat struct {
+	// Token unique token of ping URL of the website
+	Token string
+	// Grace additional duration a ping may be late before the website goes
+	// down
+	Grace time.Duration
+	// Started the time the website started waiting for pings
+	Started time.Time
+	// LastPing the time of the latest ping, zero when no ping is received yet
+	LastPing time.Time
+}
+
+// StatusCodeRange range of status codes from Min to Max (inclusive)
+type StatusCodeRange struct {
+	Min int
+	Max int
+}
+
+// CheckResult result of a single health check of a website
+type CheckResult struct {
+	WebsiteID string
+	Time      time.Time
+	// Healthy whether the website is available, a degraded website is still
+	// available
+	Healthy    bool
+	State      State
+	StatusCode int
+	// Latency total duration of the check
+	Latency time.Duration
+	Timings Timings
+	Error   string
+	// FailedAssertion the assertion that is not met by the response, empty
+	// when the check fails for other reasons
+	FailedAssertion string
+	// Certificate the certificate presented by the website, nil when the
+	// website does not use TLS
+	Certificate *Certificate
+	// Redirects URLs of followed redirects in order, the last one is the URL
+	// of the final response. Empty when the website does not redirect
+	Redirects []string
+	// Attempts number of attempts of the check including retries, the result
+	// is of the last attempt
+	Attempts int
+	// Maintenance whether the check happened within a maintenance window of
+	// the website. Such result does not change state of the website and is
+	// excluded from uptime
+	Maintenance bool
+}
+
+// MaintenanceWindow period of planned maintenance of a website, or of every
+// website with a tag. It is either one-off (from Start until End) or
+// recurring (starting on every time of Schedule, lasting Duration)
+type MaintenanceWindow struct {
+	ID string
+	// Description optional note of the maintenance, e.g. "database upgrade"
+	Description string
+	// WebsiteID the website the window applies to, empty when it applies by
+	// tag
+	WebsiteID string
+	// Tag the window applies to every website with this tag, empty when it
+	// applies to a single website
+	Tag string
+	// Start and End period of one-off window (End is exclusive)
+	Start time.Time
+	End   time.Time
+	// Schedule cron expression of every start of recurring window, each one
+	// lasting Duration
+	Schedule string
+	Duration time.Duration
+}
+
+// Notification state transition of a website reported to notifiers
+type Notification struct {
+	WebsiteID string
+	URL       string
+	OldState  State
+	NewState  State
+	// LastError error of the check that caused the transition, empty when
+	// the check succeeded
+	LastError string
+	Time      time.Time
+}
+
+// Delivery outcome of sending a notification to a webhook
+type Delivery struct {
+	Notification Notification
+	// Webhook scheme and host of URL the notification is sent to, the rest
+	// of URL is omitted since it often holds a secret token
+	Webhook string
+	// Time when the last attempt is finished
+	Time time.Time
+	// Attempts number of attempts including retries
+	Attempts int
+	// StatusCode HTTP status code of the last attempt, zero when there was no
+	// response
+	StatusCode int
+	// Error reason the last attempt failed, empty when delivered
+	Error     string
+	Delivered bool
+}
+
+// Certificate TLS certificate presented by a website
+type Certificate struct {
+	// NotAfter the time the certificate expires
+	NotAfter time.Time
+	// Issuer distinguished name of the issuer of the certificate
+	Issuer string
+	// DNSNames subject alternative names of the certificate
+	DNSNames []string
+	// ChainValid whether the certificate chain is trusted and valid for
+	// the host of the website
+	ChainValid bool
+}
+
+// Timings durations of every phase of a HTTP check. A phase that does not
+// happen (e.g. TLS handshake of plain HTTP, or DNS lookup of an IP address)
+// has zero duration
+type Timings struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// FirstByte duration since the check is started until the first byte of
+	// the response is received
+	FirstByte time.Duration
+}
+
+// clone returns a copy of the website that does not share any memory with
+// the original one
+func (web Website) clone() Website {
+	web.Tags = cloneStrings(web.Tags)
+	web.Request.Headers = cloneStringMap(web.Request.Headers)
+	web.Assertions = web.Assertions.clone()
+	web.DNS.Expected = cloneStrings(web.DNS.Expected)
+	web.Certificate = web.Certificate.clone()
+	return web
+}
+
+// clone returns a copy of the check result that does not share any memory
+// with the original one
+func (result CheckResult) clone() CheckResult {
+	result.Certificate = result.Certificate.clone()
+	result.Redirects = cloneStrings(result.Redirects)
+	return result
+}
+
+func (certificate *Certificate) clone() *Certificate {
+	if certificate == nil {
+		return nil
+	}
+	cloned := *certificate
+	cloned.DNSNames = cloneStrings(cloned.DNSNames)
+	return &cloned
+}
+
+func (assertions Assertions) clone() Assertions {
+	assertions.StatusCodes = append([]StatusCodeRange(nil), assertions.StatusCodes...)
+	assertions.BodyContains = cloneStrings(assertions.BodyContains)
+	assertions.BodyNotContains = cloneStrings(assertions.BodyNotContains)
+	assertions.BodyMatches = cloneStrings(assertions.BodyMatches)
+	assertions.BodyNotMatches = cloneStrings(assertions.BodyNotMatches)
+	assertions.JSON = cloneStrings(assertions.JSON)
+	assertions.Headers = cloneStringMap(assertions.Headers)
+	return assertions
+}
+
+func cloneStringMap(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	cloned := make(map[string]string, len(values))
+	for name, value := range values {
+		cloned[name] = value
+	}
+	return cloned
+}
+
+func cloneStrings(values []string) []string {
+	return append([]string(nil), values...)
+}
+
+// RedactURL returns rawURL with credentials of its userinfo masked, so the
+// URL can be output (e.g. in responses, logs or notifications) without
+// leaking them. rawURL without userinfo (e.g. host:port address of TCP
+// check) is returned as is
+func RedactURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.User == nil {
+		return rawURL
+	}
+	if _, ok := parsedURL.User.Password(); ok {
+		return parsedURL.Redacted()
+	}
+	// username alone is often a token (e.g. https://token@example.com)
+	parsedURL.User = url.User("xxxxx")
+	return parsedURL.String()
+}
+
+// RedactURLs returns copy of rawURLs redacted by RedactURL
+func RedactURLs(rawURLs []string) []string {
+	if rawURLs == nil {
+		return nil
+	}
+	redacted := make([]string, len(rawURLs))
+	for i, rawURL := range rawURLs {
+		redacted[i] = RedactURL(rawURL)
+	}
+	return redacted
 }
@@ -0,0 +1,20 @@
+package updater
+
+import "time"
+
+// Clock source of time of the scheduler, it can be replaced (e.g. by tests)
+// to drive the scheduler without waiting for the real time
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// After waits for duration d to elapse and then sends the current time
+	// on the returned channel
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock Clock of the real time
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
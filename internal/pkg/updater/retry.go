@@ -0,0 +1,68 @@
+package updater
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/backoff"
+	"github.com/ajiyakin/gohealthz/internal/pkg/checker"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+var (
+	// sleepFunc waits between two attempts of a check, replaced by tests
+	sleepFunc = backoff.Sleep
+	// jitterFunc returns random fraction in [0, 1) used to spread retries
+	jitterFunc = rand.Float64
+)
+
+// checkWithRetries checks the website, a failed check is retried up to
+// number of retries of config with exponential backoff before the failure is
+// reported. Heartbeat is never retried since it does not probe the website.
+// No more attempts are made once ctx is done
+func checkWithRetries(ctx context.Context, website storage.Website, config Config) storage.CheckResult {
+	retries := config.Retries
+	if checker.TypeOf(website) == storage.CheckHeartbeat {
+		retries = 0
+	}
+	for attempt := 1; ; attempt++ {
+		result := checkOnce(ctx, website, config)
+		result.Attempts = attempt
+		if result.State != storage.StateDown || attempt > retries {
+			return result
+		}
+		delay := retryBackoff(config.RetryBackoff, attempt, jitterFunc())
+		log.Printf("website with URL: %s failed attempt %d: %s, retrying in %s", storage.RedactURL(website.URL), attempt, result.Error, delay)
+		if err := sleepFunc(ctx, delay); err != nil {
+			return result
+		}
+	}
+}
+
+// checkOnce checks the website within its own timeout, or default timeout of
+// config when the website has none
+func checkOnce(ctx context.Context, website storage.Website, config Config) storage.CheckResult {
+	timeout := website.Timeout
+	if timeout <= 0 {
+		timeout = config.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	registry := checkers
+	if config.Clock != nil {
+		registry = checkers.WithNow(config.Clock.Now)
+	}
+	return registry.Check(ctx, website)
+}
+
+// retryBackoff returns delay after the failed attempt, spread by jitter
+// (random fraction in [0, 1)) so retries of websites that fail together do
+// not hit them at once
+func retryBackoff(base time.Duration, attempt int, jitter float64) time.Duration {
+	return backoff.Jitter(backoff.Delay(base, attempt), jitter)
+}
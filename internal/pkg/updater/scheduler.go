@@ -0,0 +1,316 @@
+package updater
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/checker"
+	"github.com/ajiyakin/gohealthz/internal/pkg/cron"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// syncInterval how often the scheduler looks for websites that are added to
+// or removed from the database
+const syncInterval = time.Second
+
+// heartbeatSlack how long after the deadline of a heartbeat website it is
+// checked, so the deadline has surely passed
+const heartbeatSlack = 100 * time.Millisecond
+
+// scheduledWebsite a website waiting within schedule queue for its next check
+type scheduledWebsite struct {
+	websiteID string
+	next      time.Time
+	index     int
+}
+
+// scheduleQueue priority queue of websites ordered by their next check time,
+// it implements heap.Interface
+type scheduleQueue []*scheduledWebsite
+
+func (queue scheduleQueue) Len() int { return len(queue) }
+
+func (queue scheduleQueue) Less(i, j int) bool { return queue[i].next.Before(queue[j].next) }
+
+func (queue scheduleQueue) Swap(i, j int) {
+	queue[i], queue[j] = queue[j], queue[i]
+	queue[i].index = i
+	queue[j].index = j
+}
+
+func (queue *scheduleQueue) Push(x interface{}) {
+	entry := x.(*scheduledWebsite)
+	entry.index = len(*queue)
+	*queue = append(*queue, entry)
+}
+
+func (queue *scheduleQueue) Pop() interface{} {
+	old := *queue
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	entry.index = -1
+	*queue = old[:len(old)-1]
+	return entry
+}
+
+// finishedCheck notification sent by a worker once a website is checked
+type finishedCheck struct {
+	websiteID string
+	started   time.Time
+}
+
+// scheduler schedules every website independently based on its own interval.
+// A website is never checked again before its previous check is finished,
+// and a website whose host is saturated waits within the queue rather than
+// holding a worker
+type scheduler struct {
+	database storage.Database
+	config   Config
+	clock    Clock
+	queue    scheduleQueue
+	// queued websites within queue by their ID
+	queued map[string]*scheduledWebsite
+	// running hosts of websites that are being checked by workers, by their
+	// ID
+	running  map[string]string
+	hosts    *hostLimiter
+	jobs     chan storage.Website
+	finished chan finishedCheck
+	lastSync time.Time
+}
+
+func newScheduler(database storage.Database, config Config) *scheduler {
+	clock := config.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &scheduler{
+		database: database,
+		config:   config,
+		clock:    clock,
+		queued:   make(map[string]*scheduledWebsite),
+		running:  make(map[string]string),
+		hosts:    newHostLimiter(config.HostConcurrency),
+		jobs:     make(chan storage.Website),
+		finished: make(chan finishedCheck),
+	}
+}
+
+// startWorkers starts pool of workers checking websites sent by the
+// scheduler, checks are cancelled once ctx is done
+func (s *scheduler) startWorkers(ctx context.Context) {
+	concurrency := s.config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			for website := range s.jobs {
+				started := s.clock.Now()
+				checkWebsite(ctx, s.database, website, s.config)
+				s.finished <- finishedCheck{websiteID: website.ID, started: started}
+			}
+		}()
+	}
+}
+
+// run dispatches websites to workers whenever they are due until stop is
+// closed or ctx is done, and waits for in-flight checks before returning
+func (s *scheduler) run(ctx context.Context, stop <-chan struct{}) {
+	for {
+		now := s.clock.Now()
+		if now.Sub(s.lastSync) >= syncInterval {
+			s.sync(now)
+		}
+
+		// only send to workers when a website is due, sending to nil channel
+		// blocks forever so the case is never selected otherwise
+		var jobs chan storage.Website
+		due, next, ok := s.due(now)
+		if ok {
+			jobs = s.jobs
+		}
+
+		select {
+		case jobs <- due:
+			s.remove(due.ID)
+			host := hostOf(due.URL)
+			s.hosts.acquire(host)
+			s.running[due.ID] = host
+		case check := <-s.finished:
+			s.hosts.release(s.running[check.websiteID])
+			delete(s.running, check.websiteID)
+			s.reschedule(check, s.clock.Now())
+		case <-s.clock.After(s.wait(now, next)):
+		case <-stop:
+			s.drain()
+			return
+		case <-ctx.Done():
+			s.drain()
+			return
+		}
+	}
+}
+
+// drain stops workers once their in-flight checks are finished, so workers
+// are not blocked forever sending to finished
+func (s *scheduler) drain() {
+	close(s.jobs)
+	for len(s.running) > 0 {
+		check := <-s.finished
+		delete(s.running, check.websiteID)
+	}
+}
+
+// due returns the earliest due website whose host allows another check.
+// Websites of saturated hosts are skipped and stay within the queue until a
+// check of their host is finished. When no website can be dispatched, the
+// time the next website is due is returned instead (zero when there is none)
+func (s *scheduler) due(now time.Time) (storage.Website, time.Time, bool) {
+	var saturated []*scheduledWebsite
+	defer func() {
+		for _, entry := range saturated {
+			heap.Push(&s.queue, entry)
+		}
+	}()
+	for len(s.queue) > 0 {
+		entry := s.queue[0]
+		if entry.next.After(now) {
+			return storage.Website{}, entry.next, false
+		}
+		website, err := s.database.GetByID(entry.websiteID)
+		if err != nil {
+			s.remove(entry.websiteID)
+			continue
+		}
+		if !s.hosts.available(hostOf(website.URL)) {
+			saturated = append(saturated, heap.Pop(&s.queue).(*scheduledWebsite))
+			continue
+		}
+		return website, time.Time{}, true
+	}
+	return storage.Website{}, time.Time{}, false
+}
+
+// wait returns how long the scheduler may sleep until next (zero means
+// nothing is going to be due) or the next sync. A finished check wakes the
+// scheduler up anyway, e.g. when a due website waits for a free worker or for
+// its host
+func (s *scheduler) wait(now time.Time, next time.Time) time.Duration {
+	wait := s.lastSync.Add(syncInterval).Sub(now)
+	if !next.IsZero() {
+		if untilNext := next.Sub(now); untilNext < wait {
+			wait = untilNext
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// sync adds websites that are not scheduled yet and removes websites that are
+// deleted from database. A new website is due immediately, unless it has a
+// cron schedule which is followed from the start
+func (s *scheduler) sync(now time.Time) {
+	s.lastSync = now
+	websites, err := s.database.Get()
+	if err != nil {
+		log.Printf("unable to get list of websites to schedule: %v", err)
+		return
+	}
+	exists := make(map[string]bool, len(websites))
+	for _, website := range websites {
+		exists[website.ID] = true
+		if _, running := s.running[website.ID]; running || s.queued[website.ID] != nil {
+			continue
+		}
+		next := now
+		if schedule := cronSchedule(website); schedule != nil {
+			if next = schedule.Next(now); next.IsZero() {
+				continue
+			}
+		}
+		s.schedule(website.ID, next)
+	}
+	for websiteID := range s.queued {
+		if !exists[websiteID] {
+			s.remove(websiteID)
+		}
+	}
+}
+
+// reschedule puts a website back to the queue once its check is finished.
+// The next check is due one interval after the previous one was started, or
+// immediately when the check took longer than the interval. A heartbeat
+// website is due earlier when its deadline passes before that. A website with
+// cron schedule is due on the next time of its schedule, times missed while
+// it was being checked are skipped
+func (s *scheduler) reschedule(check finishedCheck, now time.Time) {
+	website, err := s.database.GetByID(check.websiteID)
+	if err != nil {
+		// website is deleted while being checked
+		return
+	}
+	if schedule := cronSchedule(website); schedule != nil {
+		next := schedule.Next(check.started)
+		if next.Before(now) {
+			next = schedule.Next(now)
+		}
+		if !next.IsZero() {
+			s.schedule(website.ID, next)
+		}
+		return
+	}
+	next := check.started.Add(s.interval(website))
+	if website.CheckType == storage.CheckHeartbeat {
+		// a missed ping is noticed on its deadline rather than up to an
+		// interval late
+		_, deadline := checker.HeartbeatDeadline(website)
+		if deadline = deadline.Add(heartbeatSlack); deadline.After(now) && deadline.Before(next) {
+			next = deadline
+		}
+	}
+	if next.Before(now) {
+		next = now
+	}
+	s.schedule(website.ID, next)
+}
+
+func (s *scheduler) interval(website storage.Website) time.Duration {
+	if website.Interval > 0 {
+		return website.Interval
+	}
+	return s.config.Interval
+}
+
+// cronSchedule returns parsed cron schedule of the website, nil when the
+// website is checked on interval
+func cronSchedule(website storage.Website) *cron.Schedule {
+	if website.Schedule == "" {
+		return nil
+	}
+	schedule, err := cron.Parse(website.Schedule)
+	if err != nil {
+		log.Printf("invalid schedule %q of website with URL: %s, interval is used instead: %v", website.Schedule, storage.RedactURL(website.URL), err)
+		return nil
+	}
+	return schedule
+}
+
+func (s *scheduler) schedule(websiteID string, next time.Time) {
+	entry := &scheduledWebsite{websiteID: websiteID, next: next}
+	heap.Push(&s.queue, entry)
+	s.queued[websiteID] = entry
+}
+
+func (s *scheduler) remove(websiteID string) {
+	entry, ok := s.queued[websiteID]
+	if !ok {
+		return
+	}
+	heap.Remove(&s.queue, entry.index)
+	delete(s.queued, websiteID)
+}
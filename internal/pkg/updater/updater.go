@@ -1,45 +1,328 @@
 package updater
 
 import (
+	"context"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
+	"github.com/ajiyakin/gohealthz/internal/pkg/checker"
+	"github.com/ajiyakin/gohealthz/internal/pkg/maintenance"
+	"github.com/ajiyakin/gohealthz/internal/pkg/notifier"
+	"github.com/ajiyakin/gohealthz/internal/pkg/state"
 	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
 )
 
-// StartUpdate starts (run) updater on the background and will update
-// website healthiness in a given interval
-func StartUpdate(database storage.Database, interval time.Duration) {
+// Config configurations of the updater
+type Config struct {
+	// Interval default duration between two checks of a website, used when
+	// the website has no interval of its own
+	Interval time.Duration
+	// Timeout default timeout of a check, used when the website has no
+	// timeout of its own
+	Timeout time.Duration
+	// Concurrency maximum number of websites checked at the same time
+	Concurrency int
+	// HostConcurrency maximum number of websites of the same host checked at
+	// the same time. Zero or negative means unlimited
+	HostConcurrency int
+	// Thresholds default number of consecutive check results required
+	// before the state of a website flips
+	Thresholds state.Thresholds
+	// Retries maximum number of retries of a failed check within the same
+	// cycle before the failure is reported. Zero means no retries
+	Retries int
+	// RetryBackoff delay before the first retry, doubled on every following
+	// retry and randomized by jitter
+	RetryBackoff time.Duration
+	// Clock source of time of the scheduler and of heartbeat deadlines, the
+	// real clock is used when it is nil
+	Clock Clock
+	// Notifier reports state transitions of websites on the background, so
+	// checks never wait for it. Nothing is reported when it is nil
+	Notifier notifier.Notifier
+}
+
+var (
+	// checkers probes used to check websites by their type. Timeout is
+	// applied per check since every website may have its own timeout
+	checkers = checker.NewRegistry(&http.Client{})
+)
+
+// Updater updates website healthiness on the background, every website is
+// checked independently based on its own interval
+type Updater struct {
+	database storage.Database
+	config   Config
+	// notifications queue of notifier of config, nil when there is none
+	notifications *notifier.Queue
+	mutex         sync.Mutex
+	// stop and done are nil unless the updater is running
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New initialize updater of websites within database, it does nothing until
+// it is started
+func New(database storage.Database, config Config) *Updater {
+	u := &Updater{database: database}
+	if config.Notifier != nil {
+		u.notifications = notifier.NewQueue(config.Notifier, notifier.DefaultQueueSize)
+		config.Notifier = u.notifications
+	}
+	u.config = config
+	return u
+}
+
+// Start starts (run) the updater on the background until Stop is called or
+// ctx is done. Every website is checked as soon as the updater starts, then
+// based on its own interval. Cancelling ctx also cancels in-flight checks,
+// their results are discarded, and gives up pending notifications. Starting a
+// running updater does nothing
+func (u *Updater) Start(ctx context.Context) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.stop != nil {
+		return
+	}
 	log.Printf("starting updater...")
-	ticker := time.NewTicker(interval)
-	go func(database storage.Database) {
-		for range ticker.C {
-			updateHealthiness(database)
-		}
-	}(database)
+	stop, done := make(chan struct{}), make(chan struct{})
+	u.stop, u.done = stop, done
+	if u.notifications != nil {
+		u.notifications.Start(ctx)
+	}
+	s := newScheduler(u.database, u.config)
+	s.startWorkers(ctx)
+	go func() {
+		s.run(ctx, stop)
+		close(done)
+	}()
 	log.Printf("...updater started")
 }
 
-func updateHealthiness(database storage.Database) {
-	websites, err := database.Get()
+// Stop stops scheduling new checks and blocks until in-flight checks are
+// finished and stored, and their notifications are delivered. Stopping an
+// updater that is not running does nothing
+func (u *Updater) Stop() {
+	u.mutex.Lock()
+	stop, done := u.stop, u.done
+	u.stop, u.done = nil, nil
+	u.mutex.Unlock()
+	if stop == nil {
+		return
+	}
+	log.Printf("stopping updater...")
+	close(stop)
+	<-done
+	if u.notifications != nil {
+		u.notifications.Stop()
+	}
+	log.Printf("...updater stopped")
+}
+
+// CheckWebsite checks the website with the given ID right away, regardless
+// of its schedule, the same way the scheduled checks do. The result is stored
+// and returned, storage.ErrNotFound is returned when there is no such website
+func (u *Updater) CheckWebsite(ctx context.Context, websiteID string) (storage.CheckResult, error) {
+	website, err := u.database.GetByID(websiteID)
 	if err != nil {
-		log.Printf("unable to get list of websites to update: %v", err)
+		return storage.CheckResult{}, err
+	}
+	return checkWebsite(ctx, u.database, website, u.config), nil
+}
+
+// RecordPing records a ping of the heartbeat website with the given ID
+// received at the given time. The ping is reported the same way as a
+// successful scheduled check, storage.ErrNotFound is returned when there is no
+// such website
+func (u *Updater) RecordPing(ctx context.Context, websiteID string, at time.Time) error {
+	website, err := u.database.Update(websiteID, func(website storage.Website) storage.Website {
+		if at.After(website.Heartbeat.LastPing) {
+			website.Heartbeat.LastPing = at
+		}
+		return website
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("received ping of heartbeat website with URL: %s", storage.RedactURL(website.URL))
+	result := storage.CheckResult{
+		WebsiteID: website.ID,
+		Time:      at,
+		Healthy:   true,
+		State:     storage.StateUp,
+	}
+	report(ctx, u.database, website, result, u.config)
+	return nil
+}
+
+// checkWebsite checks the website and stores its result, unless the check is
+// cancelled or there is nothing to report yet
+func checkWebsite(ctx context.Context, database storage.Database, website storage.Website, config Config) storage.CheckResult {
+	result := checkWithRetries(ctx, website, config)
+	if ctx.Err() != nil {
+		// the check is cancelled rather than failed, so it is not reported
+		log.Printf("check of website with URL: %s is cancelled", storage.RedactURL(website.URL))
+		return result
+	}
+	if result.State == storage.StateUnknown {
+		// nothing to report yet (e.g. heartbeat waiting for its first ping)
+		return result
+	}
+	report(ctx, database, website, result, config)
+	return result
+}
+
+// report moves the website to its next state based on the result and stores
+// the result. Result within maintenance window is stored flagged, without
+// changing state of the website
+func report(ctx context.Context, database storage.Database, website storage.Website, result storage.CheckResult, config Config) {
+	if inMaintenance(database, website, result.Time) {
+		result.Maintenance = true
+		log.Printf("website with URL: %s is under maintenance, state %s is not reported", storage.RedactURL(website.URL), result.State)
+		saveCheckResult(database, result)
 		return
 	}
-	for _, website := range websites {
-		response, err := http.Get(website.URL)
-		if err != nil {
-			log.Printf("unable to get request for URL: %s. error: %v", website.URL, err)
-			website.Healthy = false
-			continue
+	switch result.State {
+	case storage.StateDown:
+		if result.Attempts > 1 {
+			log.Printf("website with URL: %s is not healthy after %d attempts: %s", storage.RedactURL(website.URL), result.Attempts, result.Error)
+			break
 		}
-		if response.StatusCode != http.StatusOK {
-			log.Printf("website with URL: %s is not healthy. response code: %d", website.URL, response.StatusCode)
-			website.Healthy = false
+		log.Printf("website with URL: %s is not healthy: %s", storage.RedactURL(website.URL), result.Error)
+	case storage.StateDegraded:
+		if result.Error != "" {
+			log.Printf("website with URL: %s is degraded: %s", storage.RedactURL(website.URL), result.Error)
+			break
 		}
-		if err = database.Save(website); err != nil {
-			log.Printf("unable to save (update) to database: %v", err)
+		log.Printf("website with URL: %s is degraded. latency: %s", storage.RedactURL(website.URL), result.Latency)
+	}
+
+	if !saveState(ctx, database, result, config) {
+		return
+	}
+	saveCheckResult(database, result)
+}
+
+// inMaintenance reports whether the website is within one of its maintenance
+// windows at t
+func inMaintenance(database storage.Database, website storage.Website, t time.Time) bool {
+	windows, err := database.GetMaintenanceWindows()
+	if err != nil {
+		log.Printf("unable to get maintenance windows from database: %v", err)
+		return false
+	}
+	return maintenance.InMaintenance(windows, website, t)
+}
+
+// saveState atomically moves the website to its next state based on the
+// check result, and reports the transition to notifier of config. Changes
+// made to the website while it was being checked are kept. It returns false
+// when the result is not reported, because the website is deleted in the
+// meantime or the result is outdated by a newer ping of a heartbeat website
+func saveState(ctx context.Context, database storage.Database, result storage.CheckResult, config Config) bool {
+	var previous storage.Website
+	stale := false
+	next, err := database.Update(result.WebsiteID, func(website storage.Website) storage.Website {
+		previous = website
+		if website.CheckType == storage.CheckHeartbeat && website.Heartbeat.LastPing.After(result.Time) {
+			stale = true
+			return website
 		}
+		next := state.Next(website, result.State, config.Thresholds)
+		if result.Certificate != nil {
+			next.Certificate = result.Certificate
+		}
+		return next
+	})
+	if err == storage.ErrNotFound {
+		log.Printf("website with id: %s is deleted while being checked", result.WebsiteID)
+		return false
+	}
+	if err != nil {
+		log.Printf("unable to save (update) to database: %v", err)
+		return false
+	}
+	if stale {
+		log.Printf("heartbeat website with URL: %s is pinged while being checked, result is discarded", storage.RedactURL(previous.URL))
+		return false
+	}
+	if next.State != previous.State {
+		log.Printf("website with URL: %s changed state from %s to %s", storage.RedactURL(previous.URL), previous.State, next.State)
+	}
+	if config.Notifier != nil && notifier.ShouldNotify(previous.State, next.State) {
+		config.Notifier.Notify(ctx, storage.Notification{
+			WebsiteID: previous.ID,
+			URL:       storage.RedactURL(previous.URL),
+			OldState:  previous.State,
+			NewState:  next.State,
+			LastError: result.Error,
+			Time:      result.Time,
+		})
+	}
+	return true
+}
+
+func saveCheckResult(database storage.Database, result storage.CheckResult) {
+	err := database.SaveCheckResult(result)
+	if err == storage.ErrNotFound {
+		log.Printf("website with id: %s is deleted while being checked", result.WebsiteID)
+		return
+	}
+	if err != nil {
+		log.Printf("unable to save check result to database: %v", err)
+	}
+}
+
+// hostLimiter limits number of concurrent checks for every host. It is only
+// used by the goroutine of the scheduler, so it never blocks and needs no
+// locking
+type hostLimiter struct {
+	limit int
+	// running number of running checks by host, hosts without running
+	// checks are removed
+	running map[string]int
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit:   limit,
+		running: make(map[string]int),
+	}
+}
+
+// hostOf returns host of rawURL (or host:port address) checks are limited by
+func hostOf(rawURL string) string {
+	if parsedURL, err := url.Parse(rawURL); err == nil && parsedURL.Hostname() != "" {
+		return parsedURL.Hostname()
+	}
+	if hostname, _, err := net.SplitHostPort(rawURL); err == nil {
+		// address of TCP check (host:port) is not an URL
+		return hostname
+	}
+	return rawURL
+}
+
+// available reports whether another check of host is allowed
+func (limiter *hostLimiter) available(host string) bool {
+	return limiter.limit <= 0 || limiter.running[host] < limiter.limit
+}
+
+// acquire counts a check of host as running
+func (limiter *hostLimiter) acquire(host string) {
+	if limiter.limit > 0 {
+		limiter.running[host]++
+	}
+}
+
+// release counts a check of host as finished
+func (limiter *hostLimiter) release(host string) {
+	if limiter.running[host] <= 1 {
+		delete(limiter.running, host)
+		return
 	}
+	limiter.running[host]--
 }
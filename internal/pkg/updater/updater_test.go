@@ -0,0 +1,1407 @@
+package updater
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/backoff"
+	"github.com/ajiyakin/gohealthz/internal/pkg/checker"
+	"github.com/ajiyakin/gohealthz/internal/pkg/state"
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// concurrencyCounter records number of requests and maximum number of
+// requests served at the same time by a test server
+type concurrencyCounter struct {
+	mutex    sync.Mutex
+	current  map[string]int
+	maximum  map[string]int
+	requests map[string]int
+	total    int
+	peak     int
+	inFlight int
+}
+
+func newConcurrencyCounter() *concurrencyCounter {
+	return &concurrencyCounter{
+		current:  make(map[string]int),
+		maximum:  make(map[string]int),
+		requests: make(map[string]int),
+	}
+}
+
+func (counter *concurrencyCounter) handler(delay time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		counter.mutex.Lock()
+		counter.current[r.URL.Path]++
+		counter.requests[r.URL.Path]++
+		counter.total++
+		counter.inFlight++
+		if counter.current[r.URL.Path] > counter.maximum[r.URL.Path] {
+			counter.maximum[r.URL.Path] = counter.current[r.URL.Path]
+		}
+		if counter.inFlight > counter.peak {
+			counter.peak = counter.inFlight
+		}
+		counter.mutex.Unlock()
+		time.Sleep(delay)
+		counter.mutex.Lock()
+		counter.current[r.URL.Path]--
+		counter.inFlight--
+		counter.mutex.Unlock()
+	}
+}
+
+func (counter *concurrencyCounter) snapshot() (total, peak int) {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	return counter.total, counter.peak
+}
+
+func (counter *concurrencyCounter) requestsOf(path string) int {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	return counter.requests[path]
+}
+
+func saveWebsites(t *testing.T, database storage.Database, baseURL string, count int) {
+	for i := 0; i < count; i++ {
+		err := database.Save(storage.Website{
+			ID:  fmt.Sprintf("%d", i),
+			URL: fmt.Sprintf("%s/%d", baseURL, i),
+		})
+		if err != nil {
+			t.Errorf("unable to save website: %v", err)
+		}
+	}
+}
+
+// startTestScheduler starts updater, and returns function to stop it
+func startTestScheduler(database storage.Database, config Config) func() {
+	u := New(database, config)
+	u.Start(context.Background())
+	return u.Stop
+}
+
+// waitFor waits until condition is met or fails the test after a while
+func waitFor(t *testing.T, description string, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSchedulerLimitsConcurrency(t *testing.T) {
+	// arrange
+	counter := newConcurrencyCounter()
+	server := httptest.NewServer(counter.handler(20 * time.Millisecond))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	saveWebsites(t, database, server.URL, 20)
+
+	// action
+	stop := startTestScheduler(database, Config{Interval: time.Hour, Concurrency: 4})
+	waitFor(t, "every website to be checked", func() bool {
+		total, _ := counter.snapshot()
+		return total >= 20
+	})
+	stop()
+
+	// acceptance
+	total, peak := counter.snapshot()
+	if total != 20 {
+		t.Errorf("expected 20 requests, got %d", total)
+	}
+	if peak > 4 {
+		t.Errorf("expected at most 4 concurrent checks, got %d", peak)
+	}
+	if peak < 2 {
+		t.Errorf("expected websites to be checked concurrently, got %d concurrent checks", peak)
+	}
+}
+
+func TestSchedulerLimitsHostConcurrency(t *testing.T) {
+	// arrange
+	counter := newConcurrencyCounter()
+	server := httptest.NewServer(counter.handler(10 * time.Millisecond))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	saveWebsites(t, database, server.URL, 10)
+
+	// action
+	stop := startTestScheduler(database, Config{Interval: time.Hour, Concurrency: 5, HostConcurrency: 1})
+	waitFor(t, "every website to be checked", func() bool {
+		total, _ := counter.snapshot()
+		return total >= 10
+	})
+	stop()
+
+	// acceptance
+	if _, peak := counter.snapshot(); peak != 1 {
+		t.Errorf("expected at most 1 concurrent check for the same host, got %d", peak)
+	}
+}
+
+func TestSchedulerSkipsSaturatedHost(t *testing.T) {
+	// arrange
+	release := make(chan struct{})
+	checkedBusy := make(chan struct{}, 3)
+	checkedOther := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/other" {
+			close(checkedOther)
+			return
+		}
+		checkedBusy <- struct{}{}
+		<-release
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	saveWebsites(t, database, server.URL, 3)
+
+	// action
+	stop := startTestScheduler(database, Config{Interval: time.Hour, Timeout: time.Minute, Concurrency: 2, HostConcurrency: 1})
+	<-checkedBusy
+	// the same server within another host, added while its first host is
+	// saturated
+	otherURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/other"
+	if err := database.Save(storage.Website{ID: "other", URL: otherURL}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	checked := false
+	select {
+	case <-checkedOther:
+		checked = true
+	case <-time.After(5 * time.Second):
+	}
+	close(release)
+	stop()
+
+	// acceptance
+	if !checked {
+		t.Errorf("expected website of another host to be checked while the first host is saturated")
+	}
+}
+
+func TestHostLimiterRemovesIdleHosts(t *testing.T) {
+	// arrange
+	limiter := newHostLimiter(2)
+	host := hostOf("https://www.example.com/health")
+
+	// action
+	limiter.acquire(host)
+	limiter.acquire(host)
+	saturated := !limiter.available(host)
+	limiter.release(host)
+	limiter.release(host)
+
+	// acceptance
+	if !saturated {
+		t.Errorf("expected host to be saturated by 2 checks")
+	}
+	if len(limiter.running) != 0 {
+		t.Errorf("expected idle host to be removed, got %v", limiter.running)
+	}
+}
+
+func TestSchedulerUsesIntervalOfEveryWebsite(t *testing.T) {
+	// arrange
+	counter := newConcurrencyCounter()
+	server := httptest.NewServer(counter.handler(0))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	websites := []storage.Website{
+		{ID: "fast", URL: server.URL + "/fast", Interval: 20 * time.Millisecond},
+		{ID: "slow", URL: server.URL + "/slow"},
+	}
+	for _, website := range websites {
+		if err := database.Save(website); err != nil {
+			t.Errorf("unable to save website: %v", err)
+		}
+	}
+
+	// action
+	stop := startTestScheduler(database, Config{Interval: time.Hour, Concurrency: 2})
+	waitFor(t, "fast website to be checked several times", func() bool {
+		return counter.requestsOf("/fast") >= 4
+	})
+	stop()
+
+	// acceptance
+	if actual := counter.requestsOf("/slow"); actual != 1 {
+		t.Errorf("expected website with default interval to be checked once, got %d", actual)
+	}
+}
+
+func TestSchedulerNeverOverlapsChecksOfSameWebsite(t *testing.T) {
+	// arrange
+	counter := newConcurrencyCounter()
+	server := httptest.NewServer(counter.handler(30 * time.Millisecond))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	err := database.Save(storage.Website{ID: "123", URL: server.URL + "/123", Interval: time.Millisecond})
+	if err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+
+	// action
+	stop := startTestScheduler(database, Config{Interval: time.Hour, Concurrency: 4})
+	waitFor(t, "website to be checked several times", func() bool {
+		return counter.requestsOf("/123") >= 3
+	})
+	stop()
+
+	// acceptance
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	if counter.maximum["/123"] != 1 {
+		t.Errorf("expected checks of the same website to never overlap, got %d concurrent checks", counter.maximum["/123"])
+	}
+}
+
+func TestCheckWebsiteUsesTimeoutOfWebsite(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{ID: "123", URL: server.URL, Healthy: true, Timeout: 20 * time.Millisecond}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+
+	// action
+	checkWebsite(context.Background(), database, website, Config{Timeout: time.Minute})
+
+	// acceptance
+	results, err := database.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 check result, got %d", len(results))
+	}
+	if results[0].Healthy || results[0].Error == "" {
+		t.Errorf("expected check to time out, got %#v", results[0])
+	}
+	if results[0].Latency > 150*time.Millisecond {
+		t.Errorf("expected check to be cancelled after website timeout, took %s", results[0].Latency)
+	}
+}
+
+func TestCheckWebsiteRecordsTimings(t *testing.T) {
+	// arrange
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	defaultCheckers := checkers
+	checkers = checker.NewRegistry(server.Client())
+	defer func() { checkers = defaultCheckers }()
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{ID: "123", URL: server.URL}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+
+	// action
+	checkWebsite(context.Background(), database, website, Config{Timeout: time.Minute})
+
+	// acceptance
+	results, err := database.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 check result, got %d", len(results))
+	}
+	result := results[0]
+	if result.State != storage.StateUp {
+		t.Errorf("expected state %s, got %s", storage.StateUp, result.State)
+	}
+	if result.Timings.Connect <= 0 || result.Timings.TLSHandshake <= 0 {
+		t.Errorf("expected connect and TLS handshake durations to be recorded, got %#v", result.Timings)
+	}
+	if result.Timings.FirstByte < 10*time.Millisecond || result.Latency < result.Timings.FirstByte {
+		t.Errorf("expected first byte duration between response delay and total latency, got %#v with latency %s", result.Timings, result.Latency)
+	}
+	actual, err := database.GetByID("123")
+	if err != nil {
+		t.Errorf("unable to get website: %v", err)
+	}
+	if actual.Certificate == nil || !actual.Certificate.NotAfter.Equal(server.Certificate().NotAfter) {
+		t.Errorf("expected certificate of the server to be stored with the website, got %+v", actual.Certificate)
+	}
+}
+
+func TestCheckWebsiteDegradedAndRecovered(t *testing.T) {
+	// arrange
+	delay := 50 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{ID: "123", URL: server.URL, DegradedLatency: 30 * time.Millisecond}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+
+	// action
+	checkWebsite(context.Background(), database, website, Config{Timeout: time.Minute})
+	degraded, err := database.GetByID("123")
+	if err != nil {
+		t.Errorf("unable to get website: %v", err)
+	}
+	delay = 0
+	checkWebsite(context.Background(), database, website, Config{Timeout: time.Minute})
+	recovered, err := database.GetByID("123")
+	if err != nil {
+		t.Errorf("unable to get website: %v", err)
+	}
+
+	// acceptance
+	if degraded.State != storage.StateDegraded || !degraded.Healthy {
+		t.Errorf("expected website to be degraded but healthy, got %#v", degraded)
+	}
+	if recovered.State != storage.StateUp || !recovered.Healthy {
+		t.Errorf("expected website to be up, got %#v", recovered)
+	}
+}
+
+func TestCheckWebsiteEvaluatesAssertions(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/no-content":
+			w.WriteHeader(http.StatusNoContent)
+		case "/redirect":
+			w.Header().Set("Location", "/elsewhere")
+			w.WriteHeader(http.StatusMovedPermanently)
+		default:
+			w.Write([]byte("database: down"))
+		}
+	}))
+	defer server.Close()
+	defaultCheckers := checkers
+	checkers = checker.NewRegistry(&http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }})
+	defer func() { checkers = defaultCheckers }()
+	websites := []struct {
+		website storage.Website
+		state   storage.State
+	}{
+		{
+			storage.Website{ID: "no-content", URL: server.URL + "/no-content", Assertions: storage.Assertions{
+				StatusCodes: []storage.StatusCodeRange{{Min: 200, Max: 299}},
+			}},
+			storage.StateUp,
+		}, {
+			storage.Website{ID: "redirect", URL: server.URL + "/redirect", Assertions: storage.Assertions{
+				StatusCodes: []storage.StatusCodeRange{{Min: 301, Max: 301}},
+			}},
+			storage.StateUp,
+		}, {
+			storage.Website{ID: "body", URL: server.URL + "/body", Assertions: storage.Assertions{
+				BodyNotContains: []string{"down"},
+			}},
+			storage.StateDown,
+		},
+	}
+	database := storage.NewInMemoryDatabase()
+
+	for _, tt := range websites {
+		t.Run(tt.website.ID, func(t *testing.T) {
+			if err := database.Save(tt.website); err != nil {
+				t.Errorf("unable to save website: %v", err)
+			}
+
+			// action
+			checkWebsite(context.Background(), database, tt.website, Config{Timeout: time.Minute})
+
+			// acceptance
+			actual, err := database.GetByID(tt.website.ID)
+			if err != nil {
+				t.Errorf("unable to get website: %v", err)
+			}
+			if actual.State != tt.state {
+				t.Errorf("expected state %s, got %s", tt.state, actual.State)
+			}
+		})
+	}
+}
+
+func TestCheckWebsiteReportsFailedJSONAssertion(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status": "degraded", "checks": [{"healthy": true}]}`))
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{ID: "123", URL: server.URL, Assertions: storage.Assertions{
+		JSON: []string{`$.checks[*].healthy == true`, `$.status == "ok"`},
+	}}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+
+	// action
+	checkWebsite(context.Background(), database, website, Config{Timeout: time.Minute})
+
+	// acceptance
+	results, err := database.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 check result, got %d", len(results))
+	}
+	if results[0].State != storage.StateDown {
+		t.Errorf("expected state %s, got %s", storage.StateDown, results[0].State)
+	}
+	if expected := `json $.status == "ok"`; results[0].FailedAssertion != expected {
+		t.Errorf("expected failed assertion %s, got %s", expected, results[0].FailedAssertion)
+	}
+}
+
+func TestCheckWebsiteRecoversAfterSuccessThreshold(t *testing.T) {
+	// arrange
+	statusCode := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCode)
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{ID: "123", URL: server.URL, State: storage.StateUp, Healthy: true}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	config := Config{Timeout: time.Minute, Thresholds: state.Thresholds{Failure: 2, Success: 2}}
+	expectedStates := []struct {
+		statusCode int
+		state      storage.State
+	}{
+		{http.StatusInternalServerError, storage.StateUp},
+		{http.StatusInternalServerError, storage.StateDown},
+		{http.StatusOK, storage.StateDown},
+		{http.StatusOK, storage.StateUp},
+	}
+
+	for index, expected := range expectedStates {
+		statusCode = expected.statusCode
+
+		// action
+		checkWebsite(context.Background(), database, website, config)
+
+		// acceptance
+		actual, err := database.GetByID("123")
+		if err != nil {
+			t.Errorf("unable to get website: %v", err)
+		}
+		if actual.State != expected.state {
+			t.Errorf("check %d: expected state %s, got %s", index+1, expected.state, actual.State)
+		}
+		if actual.Healthy != (expected.state == storage.StateUp) {
+			t.Errorf("check %d: expected healthy %v, got %v", index+1, expected.state == storage.StateUp, actual.Healthy)
+		}
+	}
+}
+
+func TestCheckWebsiteOverTCP(t *testing.T) {
+	// arrange
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connection.Write([]byte("+OK ready\r\n"))
+			connection.Close()
+		}
+	}()
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{
+		ID:        "123",
+		CheckType: storage.CheckTCP,
+		URL:       listener.Addr().String(),
+		TCP:       storage.TCPOptions{Expect: "+OK"},
+	}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+
+	// action
+	checkWebsite(context.Background(), database, website, Config{Timeout: time.Second})
+
+	// acceptance
+	results, err := database.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 check result, got %d", len(results))
+	}
+	if results[0].State != storage.StateUp {
+		t.Errorf("expected state %s, got %s (error: %s)", storage.StateUp, results[0].State, results[0].Error)
+	}
+	actual, err := database.GetByID("123")
+	if err != nil {
+		t.Errorf("unable to get website: %v", err)
+	}
+	if !actual.Healthy {
+		t.Errorf("expected website to be healthy")
+	}
+}
+
+func TestCheckWebsiteOverGRPC(t *testing.T) {
+	// arrange
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		// length-prefixed HealthCheckResponse{status: NOT_SERVING}
+		w.Write([]byte{0, 0, 0, 0, 2, 0x08, 2})
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{
+		ID:        "123",
+		CheckType: storage.CheckGRPC,
+		URL:       strings.Replace(server.URL, "http://", "grpc://", 1),
+		State:     storage.StateUp,
+		Healthy:   true,
+	}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+
+	// action
+	checkWebsite(context.Background(), database, website, Config{Timeout: time.Second})
+
+	// acceptance
+	actual, err := database.GetByID("123")
+	if err != nil {
+		t.Errorf("unable to get website: %v", err)
+	}
+	if actual.State != storage.StateDown || actual.Healthy {
+		t.Errorf("expected NOT_SERVING website to be down, got %s", actual.State)
+	}
+	results, err := database.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	if len(results) != 1 || results[0].FailedAssertion != "grpc status SERVING" {
+		t.Errorf("expected failed gRPC status assertion, got %+v", results)
+	}
+}
+
+func TestCheckWebsiteOverWebSocket(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" {
+			http.Error(w, "websocket only", http.StatusBadRequest)
+			return
+		}
+		hash := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		w.Header().Set("Upgrade", "websocket")
+		w.Header().Set("Connection", "Upgrade")
+		w.Header().Set("Sec-WebSocket-Accept", base64.StdEncoding.EncodeToString(hash[:]))
+		w.WriteHeader(http.StatusSwitchingProtocols)
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	websites := []storage.Website{
+		{ID: "http", URL: server.URL},
+		{ID: "websocket", CheckType: storage.CheckWebSocket, URL: strings.Replace(server.URL, "http://", "ws://", 1)},
+	}
+	expectedStates := map[string]storage.State{"http": storage.StateDown, "websocket": storage.StateUp}
+
+	for _, website := range websites {
+		if err := database.Save(website); err != nil {
+			t.Errorf("unable to save website: %v", err)
+		}
+
+		// action
+		checkWebsite(context.Background(), database, website, Config{Timeout: time.Second})
+
+		// acceptance
+		actual, err := database.GetByID(website.ID)
+		if err != nil {
+			t.Errorf("unable to get website: %v", err)
+		}
+		if actual.State != expectedStates[website.ID] {
+			t.Errorf("expected %s website to be %s, got %s", website.ID, expectedStates[website.ID], actual.State)
+		}
+	}
+}
+
+func TestCheckWebsiteHeartbeat(t *testing.T) {
+	// arrange
+	database := storage.NewInMemoryDatabase()
+	websites := []storage.Website{
+		{
+			ID:        "waiting",
+			CheckType: storage.CheckHeartbeat,
+			Interval:  time.Hour,
+			State:     storage.StateUnknown,
+			Heartbeat: storage.Heartbeat{Token: "a", Started: time.Now()},
+		},
+		{
+			ID:        "missed",
+			CheckType: storage.CheckHeartbeat,
+			Interval:  time.Hour,
+			State:     storage.StateUp,
+			Healthy:   true,
+			Heartbeat: storage.Heartbeat{Token: "b", Started: time.Now().Add(-24 * time.Hour), LastPing: time.Now().Add(-2 * time.Hour)},
+		},
+	}
+	expected := map[string]struct {
+		state   storage.State
+		results int
+	}{
+		"waiting": {storage.StateUnknown, 0},
+		"missed":  {storage.StateDown, 1},
+	}
+
+	for _, website := range websites {
+		if err := database.Save(website); err != nil {
+			t.Errorf("unable to save website: %v", err)
+		}
+
+		// action
+		checkWebsite(context.Background(), database, website, Config{Timeout: time.Second})
+
+		// acceptance
+		actual, err := database.GetByID(website.ID)
+		if err != nil {
+			t.Errorf("unable to get website: %v", err)
+		}
+		if actual.State != expected[website.ID].state {
+			t.Errorf("expected %s heartbeat to be %s, got %s", website.ID, expected[website.ID].state, actual.State)
+		}
+		results, err := database.GetCheckResults(website.ID, time.Time{}, time.Time{})
+		if err != nil {
+			t.Errorf("unable to get check results: %v", err)
+		}
+		if len(results) != expected[website.ID].results {
+			t.Errorf("expected %d check results of %s heartbeat, got %d", expected[website.ID].results, website.ID, len(results))
+		}
+	}
+}
+
+func TestCheckWebsiteHeartbeatByClock(t *testing.T) {
+	// arrange
+	database := storage.NewInMemoryDatabase()
+	started := time.Now()
+	website := storage.Website{
+		ID:        "123",
+		CheckType: storage.CheckHeartbeat,
+		Interval:  time.Hour,
+		State:     storage.StateUp,
+		Healthy:   true,
+		Heartbeat: storage.Heartbeat{Token: "a", Started: started, LastPing: started},
+	}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	clock := &fakeClock{now: started.Add(2 * time.Hour)}
+
+	// action
+	result := checkWebsite(context.Background(), database, website, Config{Timeout: time.Second, Clock: clock})
+
+	// acceptance
+	if result.State != storage.StateDown {
+		t.Errorf("expected ping to be missed by the clock of config, got %s", result.State)
+	}
+}
+
+func TestRecordPing(t *testing.T) {
+	// arrange
+	now := time.Now()
+	database := storage.NewInMemoryDatabase()
+	websites := []storage.Website{
+		{ID: "down", CheckType: storage.CheckHeartbeat, Interval: time.Hour, State: storage.StateDown, Heartbeat: storage.Heartbeat{Token: "a"}},
+		{ID: "maintenance", CheckType: storage.CheckHeartbeat, Interval: time.Hour, State: storage.StateDown, Tags: []string{"deploy"}, Heartbeat: storage.Heartbeat{Token: "b"}},
+	}
+	for _, website := range websites {
+		if err := database.Save(website); err != nil {
+			t.Errorf("unable to save website: %v", err)
+		}
+	}
+	err := database.SaveMaintenanceWindow(storage.MaintenanceWindow{ID: "1", Tag: "deploy", Start: now.Add(-time.Minute), End: now.Add(time.Hour)})
+	if err != nil {
+		t.Errorf("unable to save maintenance window: %v", err)
+	}
+	u := New(database, Config{Thresholds: state.Thresholds{Success: 2}})
+
+	// action
+	for _, website := range websites {
+		if err = u.RecordPing(context.Background(), website.ID, now); err != nil {
+			t.Errorf("unable to record ping: %v", err)
+		}
+	}
+
+	// acceptance
+	for _, website := range websites {
+		actual, _ := database.GetByID(website.ID)
+		if actual.State != storage.StateDown || !actual.Heartbeat.LastPing.Equal(now) {
+			t.Errorf("expected %s heartbeat to stay down with last ping recorded, got %s %s", website.ID, actual.State, actual.Heartbeat.LastPing)
+		}
+		results, _ := database.GetCheckResults(website.ID, time.Time{}, time.Time{})
+		if len(results) != 1 || results[0].State != storage.StateUp || results[0].Maintenance != (website.ID == "maintenance") {
+			t.Errorf("expected up result of %s flagged maintenance %v, got %#v", website.ID, website.ID == "maintenance", results)
+		}
+	}
+	if err = u.RecordPing(context.Background(), "down", now.Add(time.Minute)); err != nil {
+		t.Errorf("unable to record ping: %v", err)
+	}
+	if actual, _ := database.GetByID("down"); actual.State != storage.StateUp {
+		t.Errorf("expected heartbeat to be up after success threshold, got %s", actual.State)
+	}
+	if err = u.RecordPing(context.Background(), "unknown", now); err != storage.ErrNotFound {
+		t.Errorf("expected error not found, got %v", err)
+	}
+}
+
+func TestRecordPingNotifiesRecovery(t *testing.T) {
+	// arrange
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{
+		ID:        "123",
+		URL:       "nightly backup",
+		CheckType: storage.CheckHeartbeat,
+		Interval:  time.Hour,
+		State:     storage.StateDown,
+		Heartbeat: storage.Heartbeat{Token: "a", LastPing: time.Now().Add(-2 * time.Hour)},
+	}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	recorder := &notificationRecorder{}
+	u := New(database, Config{Interval: time.Hour, Thresholds: state.Thresholds{Success: 1}, Notifier: recorder})
+
+	// action
+	if err := u.RecordPing(context.Background(), "123", time.Now()); err != nil {
+		t.Errorf("unable to record ping: %v", err)
+	}
+	u.Start(context.Background())
+	u.Stop()
+
+	// acceptance
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if len(recorder.notifications) != 1 {
+		t.Fatalf("expected 1 notification, got %#v", recorder.notifications)
+	}
+	if up := recorder.notifications[0]; up.WebsiteID != "123" || up.OldState != storage.StateDown || up.NewState != storage.StateUp {
+		t.Errorf("unexpected notification of heartbeat going up: %#v", up)
+	}
+}
+
+func TestCheckWebsiteHeartbeatPingedWhileBeingChecked(t *testing.T) {
+	// arrange
+	now := time.Now()
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{
+		ID:        "123",
+		CheckType: storage.CheckHeartbeat,
+		Interval:  time.Hour,
+		State:     storage.StateUp,
+		Heartbeat: storage.Heartbeat{Token: "a", LastPing: now.Add(time.Minute)},
+	}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	result := storage.CheckResult{WebsiteID: "123", Time: now, State: storage.StateDown, Error: "no ping received"}
+
+	// action
+	reported := saveState(context.Background(), database, result, Config{Thresholds: state.Thresholds{Failure: 1}})
+
+	// acceptance
+	actual, _ := database.GetByID("123")
+	if reported || actual.State != storage.StateUp {
+		t.Errorf("expected outdated result to be discarded, got state %s", actual.State)
+	}
+}
+
+func TestSchedulerChecksHeartbeatOnItsDeadline(t *testing.T) {
+	started := time.Date(2019, 3, 8, 12, 0, 0, 0, time.UTC)
+	rescheduleTests := []struct {
+		testName  string
+		heartbeat storage.Heartbeat
+		expected  time.Time
+	}{
+		{"deadline after interval", storage.Heartbeat{Grace: time.Minute, LastPing: started}, started.Add(time.Hour)},
+		{"deadline within interval", storage.Heartbeat{Grace: time.Minute, LastPing: started.Add(-50 * time.Minute)}, started.Add(11*time.Minute + heartbeatSlack)},
+		{"waiting for first ping", storage.Heartbeat{Started: started.Add(-30 * time.Minute)}, started.Add(30*time.Minute + heartbeatSlack)},
+		{"deadline missed", storage.Heartbeat{LastPing: started.Add(-2 * time.Hour)}, started.Add(time.Hour)},
+	}
+
+	for _, tt := range rescheduleTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// arrange
+			database := storage.NewInMemoryDatabase()
+			website := storage.Website{ID: "1234", CheckType: storage.CheckHeartbeat, Interval: time.Hour, Heartbeat: tt.heartbeat}
+			if err := database.Save(website); err != nil {
+				t.Fatalf("unable to save website: %v", err)
+			}
+			s := newScheduler(database, Config{Interval: time.Minute, Clock: &fakeClock{now: started}})
+
+			// action
+			s.reschedule(finishedCheck{websiteID: website.ID, started: started}, started)
+
+			// acceptance
+			if actual := s.queued[website.ID].next; !actual.Equal(tt.expected) {
+				t.Errorf("expected next check at %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCheckWebsiteRetriesFailedCheck(t *testing.T) {
+	// arrange
+	var delays []time.Duration
+	sleepFunc = func(ctx context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return nil
+	}
+	jitterFunc = func() float64 { return 0.5 }
+	defer func() { sleepFunc, jitterFunc = backoff.Sleep, rand.Float64 }()
+	retryTests := []struct {
+		testName         string
+		failures         int
+		retries          int
+		expectedState    storage.State
+		expectedAttempts int
+		expectedDelays   []time.Duration
+	}{
+		{"healthy", 0, 3, storage.StateUp, 1, nil},
+		{"recovered", 2, 3, storage.StateUp, 3, []time.Duration{75 * time.Millisecond, 150 * time.Millisecond}},
+		{"retries exhausted", 5, 2, storage.StateDown, 3, []time.Duration{75 * time.Millisecond, 150 * time.Millisecond}},
+		{"no retries", 1, 0, storage.StateDown, 1, nil},
+	}
+
+	for _, tt := range retryTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			delays = nil
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+			database := storage.NewInMemoryDatabase()
+			website := storage.Website{ID: "123", URL: server.URL, State: storage.StateUnknown}
+			if err := database.Save(website); err != nil {
+				t.Errorf("unable to save website: %v", err)
+			}
+
+			// action
+			checkWebsite(context.Background(), database, website, Config{Timeout: time.Second, Retries: tt.retries, RetryBackoff: 100 * time.Millisecond})
+
+			// acceptance
+			results, err := database.GetCheckResults("123", time.Time{}, time.Time{})
+			if err != nil || len(results) != 1 {
+				t.Fatalf("expected a single check result, got %d (error: %v)", len(results), err)
+			}
+			if results[0].State != tt.expectedState || results[0].Attempts != tt.expectedAttempts {
+				t.Errorf("expected state %s after %d attempts, got %s after %d attempts", tt.expectedState, tt.expectedAttempts, results[0].State, results[0].Attempts)
+			}
+			if !reflect.DeepEqual(delays, tt.expectedDelays) {
+				t.Errorf("expected delays %v, got %v", tt.expectedDelays, delays)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	// arrange
+	backoffTests := []struct {
+		attempt  int
+		jitter   float64
+		expected time.Duration
+	}{
+		{1, 0, 500 * time.Millisecond},
+		{1, 0.99, 995 * time.Millisecond},
+		{2, 0, time.Second},
+		{3, 0.5, 3 * time.Second},
+		{10, 0, backoff.Max / 2},
+		{10, 1, backoff.Max},
+	}
+
+	for _, tt := range backoffTests {
+		t.Run(fmt.Sprint(tt.attempt, tt.jitter), func(t *testing.T) {
+			// action
+			actual := retryBackoff(time.Second, tt.attempt, tt.jitter)
+
+			// acceptance
+			if actual != tt.expected {
+				t.Errorf("expected backoff %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUpdaterStopDrainsInFlightChecks(t *testing.T) {
+	// arrange
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	if err := database.Save(storage.Website{ID: "123", URL: server.URL, State: storage.StateUnknown}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	u := New(database, Config{Interval: time.Hour, Timeout: time.Second})
+	u.Start(context.Background())
+	<-started
+
+	// action
+	u.Stop()
+
+	// acceptance
+	results, err := database.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	if len(results) != 1 || results[0].State != storage.StateUp {
+		t.Errorf("expected in-flight check to finish as up before stop returns, got %#v", results)
+	}
+	u.Stop()
+}
+
+func TestUpdaterCancelsInFlightChecks(t *testing.T) {
+	// arrange
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	if err := database.Save(storage.Website{ID: "123", URL: server.URL, State: storage.StateUnknown}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	u := New(database, Config{Interval: time.Hour, Timeout: time.Minute, Retries: 3, RetryBackoff: time.Minute})
+	u.Start(ctx)
+	<-started
+
+	// action
+	cancel()
+	stopped := make(chan struct{})
+	go func() {
+		u.Stop()
+		close(stopped)
+	}()
+
+	// acceptance
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected cancelled check to be finished without waiting for its timeout")
+	}
+	results, err := database.GetCheckResults("123", time.Time{}, time.Time{})
+	if err != nil {
+		t.Errorf("unable to get check results: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected cancelled check to not be recorded, got %#v", results)
+	}
+	website, _ := database.GetByID("123")
+	if website.State != storage.StateUnknown {
+		t.Errorf("expected state to stay %s, got %s", storage.StateUnknown, website.State)
+	}
+}
+
+func TestUpdaterChecksEveryWebsiteOnStart(t *testing.T) {
+	// arrange
+	counter := newConcurrencyCounter()
+	server := httptest.NewServer(counter.handler(0))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	saveWebsites(t, database, server.URL, 5)
+
+	// action
+	stop := startTestScheduler(database, Config{Interval: time.Hour, Concurrency: 5})
+	waitFor(t, "every website to be checked", func() bool {
+		total, _ := counter.snapshot()
+		return total >= 5
+	})
+	stop()
+
+	// acceptance
+	for i := 0; i < 5; i++ {
+		if actual := counter.requestsOf(fmt.Sprintf("/%d", i)); actual != 1 {
+			t.Errorf("expected website %d to be checked once on start, got %d", i, actual)
+		}
+	}
+}
+
+func TestUpdaterCheckWebsite(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	if err := database.Save(storage.Website{ID: "123", URL: server.URL, State: storage.StateUp, Healthy: true}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	u := New(database, Config{Interval: time.Hour, Timeout: time.Second})
+
+	// action
+	result, err := u.CheckWebsite(context.Background(), "123")
+	_, notFoundErr := u.CheckWebsite(context.Background(), "unknown")
+
+	// acceptance
+	if err != nil {
+		t.Fatalf("unable to check website: %v", err)
+	}
+	if result.State != storage.StateDown || result.StatusCode != http.StatusServiceUnavailable || result.FailedAssertion == "" {
+		t.Errorf("expected failed check result, got %#v", result)
+	}
+	website, _ := database.GetByID("123")
+	if website.State != storage.StateDown {
+		t.Errorf("expected state %s to be stored, got %s", storage.StateDown, website.State)
+	}
+	results, _ := database.GetCheckResults("123", time.Time{}, time.Time{})
+	if len(results) != 1 {
+		t.Errorf("expected check result to be stored, got %d results", len(results))
+	}
+	if notFoundErr != storage.ErrNotFound {
+		t.Errorf("expected error %v, got %v", storage.ErrNotFound, notFoundErr)
+	}
+}
+
+// blockingNotifier records notifications along with error of their context,
+// every delivery waits until release is closed
+type blockingNotifier struct {
+	release       chan struct{}
+	mutex         sync.Mutex
+	notifications []storage.Notification
+	errs          []error
+}
+
+func (recorder *blockingNotifier) Notify(ctx context.Context, notification storage.Notification) {
+	<-recorder.release
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.notifications = append(recorder.notifications, notification)
+	recorder.errs = append(recorder.errs, ctx.Err())
+}
+
+func TestUpdaterNotifiesOnBackground(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	if err := database.Save(storage.Website{ID: "123", URL: server.URL, State: storage.StateUp, Healthy: true}); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	recorder := &blockingNotifier{release: make(chan struct{})}
+	u := New(database, Config{Interval: time.Hour, Timeout: time.Second, Thresholds: state.Thresholds{Failure: 1}, Notifier: recorder})
+	u.Start(context.Background())
+	requestCtx, cancelRequest := context.WithCancel(context.Background())
+
+	// action
+	returned := make(chan struct{})
+	go func() {
+		u.CheckWebsite(requestCtx, "123")
+		close(returned)
+	}()
+
+	// acceptance
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected on-demand check to return while notification is being delivered")
+	}
+	cancelRequest()
+	close(recorder.release)
+	u.Stop()
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if len(recorder.notifications) != 1 || recorder.notifications[0].NewState != storage.StateDown {
+		t.Fatalf("expected notification of website going down to be delivered on stop, got %#v", recorder.notifications)
+	}
+	if recorder.errs[0] != nil {
+		t.Errorf("expected notification not to be cancelled along with the request, got %v", recorder.errs[0])
+	}
+}
+
+// fakeClock Clock that only moves forward when it is advanced
+type fakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	deadline time.Time
+	channel  chan time.Time
+}
+
+func (clock *fakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+func (clock *fakeClock) After(d time.Duration) <-chan time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	channel := make(chan time.Time, 1)
+	if d <= 0 {
+		channel <- clock.now
+		return channel
+	}
+	clock.waiters = append(clock.waiters, fakeClockWaiter{deadline: clock.now.Add(d), channel: channel})
+	return channel
+}
+
+// advance moves the clock to t and wakes up every waiter that is due
+func (clock *fakeClock) advance(t time.Time) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = t
+	waiters := clock.waiters[:0]
+	for _, waiter := range clock.waiters {
+		if waiter.deadline.After(t) {
+			waiters = append(waiters, waiter)
+			continue
+		}
+		waiter.channel <- t
+	}
+	clock.waiters = waiters
+}
+
+func TestSchedulerFollowsCronSchedule(t *testing.T) {
+	// arrange
+	counter := newConcurrencyCounter()
+	server := httptest.NewServer(counter.handler(0))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	websites := []storage.Website{
+		{ID: "cron", URL: server.URL + "/cron", Schedule: "*/15 * * * *"},
+		{ID: "interval", URL: server.URL + "/interval"},
+	}
+	for _, website := range websites {
+		if err := database.Save(website); err != nil {
+			t.Errorf("unable to save website: %v", err)
+		}
+	}
+	clock := &fakeClock{now: time.Date(2019, 3, 8, 10, 7, 30, 0, time.UTC)}
+	stop := startTestScheduler(database, Config{Interval: time.Hour, Concurrency: 2, Clock: clock})
+	defer stop()
+	expectedChecks := []struct {
+		at       time.Time
+		expected int
+	}{
+		{time.Date(2019, 3, 8, 10, 7, 30, 0, time.UTC), 0},
+		{time.Date(2019, 3, 8, 10, 14, 59, 0, time.UTC), 0},
+		{time.Date(2019, 3, 8, 10, 15, 0, 0, time.UTC), 1},
+		{time.Date(2019, 3, 8, 10, 29, 0, 0, time.UTC), 1},
+		{time.Date(2019, 3, 8, 10, 30, 0, 0, time.UTC), 2},
+		// missed times are skipped rather than caught up
+		{time.Date(2019, 3, 8, 11, 20, 0, 0, time.UTC), 3},
+		{time.Date(2019, 3, 8, 11, 30, 0, 0, time.UTC), 4},
+	}
+	waitFor(t, "website with interval to be checked on start", func() bool {
+		return counter.requestsOf("/interval") == 1
+	})
+
+	for _, tt := range expectedChecks {
+		// action
+		clock.advance(tt.at)
+		waitFor(t, fmt.Sprintf("%d checks at %s", tt.expected, tt.at.Format("15:04:05")), func() bool {
+			return counter.requestsOf("/cron") >= tt.expected
+		})
+		time.Sleep(20 * time.Millisecond)
+
+		// acceptance
+		if actual := counter.requestsOf("/cron"); actual != tt.expected {
+			t.Errorf("expected %d checks at %s, got %d", tt.expected, tt.at.Format("15:04:05"), actual)
+		}
+	}
+}
+
+func TestCheckWebsiteWithinMaintenanceWindow(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	websites := []storage.Website{
+		{ID: "tagged", URL: server.URL, State: storage.StateUp, Healthy: true, Tags: []string{"deploy"}},
+		{ID: "other", URL: server.URL, State: storage.StateUp, Healthy: true},
+	}
+	for _, website := range websites {
+		if err := database.Save(website); err != nil {
+			t.Errorf("unable to save website: %v", err)
+		}
+	}
+	err := database.SaveMaintenanceWindow(storage.MaintenanceWindow{
+		ID:    "1",
+		Tag:   "deploy",
+		Start: time.Now().Add(-time.Minute),
+		End:   time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Errorf("unable to save maintenance window: %v", err)
+	}
+
+	for _, website := range websites {
+		// action
+		checkWebsite(context.Background(), database, website, Config{Timeout: time.Second})
+	}
+
+	// acceptance
+	expectedStates := map[string]storage.State{"tagged": storage.StateUp, "other": storage.StateDown}
+	for websiteID, expectedState := range expectedStates {
+		actual, err := database.GetByID(websiteID)
+		if err != nil {
+			t.Errorf("unable to get website: %v", err)
+		}
+		if actual.State != expectedState {
+			t.Errorf("expected state of %s to be %s, got %s", websiteID, expectedState, actual.State)
+		}
+		results, _ := database.GetCheckResults(websiteID, time.Time{}, time.Time{})
+		if len(results) != 1 || results[0].State != storage.StateDown || results[0].Maintenance != (websiteID == "tagged") {
+			t.Errorf("expected down check result of %s flagged maintenance %v, got %#v", websiteID, websiteID == "tagged", results)
+		}
+	}
+}
+
+// notificationRecorder records notifications instead of delivering them
+type notificationRecorder struct {
+	mutex         sync.Mutex
+	notifications []storage.Notification
+}
+
+func (recorder *notificationRecorder) Notify(ctx context.Context, notification storage.Notification) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.notifications = append(recorder.notifications, notification)
+}
+
+func TestCheckWebsiteNotifiesStateTransitions(t *testing.T) {
+	// arrange
+	statusCode := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCode)
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{ID: "123", URL: server.URL, State: storage.StateUp, Healthy: true}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	recorder := &notificationRecorder{}
+	config := Config{Timeout: time.Second, Notifier: recorder}
+
+	// action
+	checkWebsite(context.Background(), database, website, config)
+	checkWebsite(context.Background(), database, website, config)
+	statusCode = http.StatusOK
+	checkWebsite(context.Background(), database, website, config)
+	checkWebsite(context.Background(), database, website, config)
+
+	// acceptance
+	if len(recorder.notifications) != 2 {
+		t.Fatalf("expected 2 notifications, got %#v", recorder.notifications)
+	}
+	down, up := recorder.notifications[0], recorder.notifications[1]
+	if down.WebsiteID != "123" || down.URL != server.URL || down.OldState != storage.StateUp || down.NewState != storage.StateDown ||
+		down.LastError == "" || down.Time.IsZero() {
+		t.Errorf("unexpected notification of website going down: %#v", down)
+	}
+	if up.OldState != storage.StateDown || up.NewState != storage.StateUp || up.LastError != "" {
+		t.Errorf("unexpected notification of website going up: %#v", up)
+	}
+}
+
+func TestCheckWebsiteNotificationRedactsCredentialsOfURL(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{ID: "123", URL: strings.Replace(server.URL, "http://", "http://user:secret@", 1), State: storage.StateUp}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	recorder := &notificationRecorder{}
+
+	// action
+	checkWebsite(context.Background(), database, website, Config{Timeout: time.Second, Thresholds: state.Thresholds{Failure: 1}, Notifier: recorder})
+
+	// acceptance
+	expected := strings.Replace(server.URL, "http://", "http://user:xxxxx@", 1)
+	if len(recorder.notifications) != 1 || recorder.notifications[0].URL != expected {
+		t.Errorf("expected notification with URL %s, got %#v", expected, recorder.notifications)
+	}
+}
+
+func TestCheckWebsiteWithinMaintenanceWindowIsNotNotified(t *testing.T) {
+	// arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	database := storage.NewInMemoryDatabase()
+	website := storage.Website{ID: "123", URL: server.URL, State: storage.StateUp, Healthy: true}
+	if err := database.Save(website); err != nil {
+		t.Errorf("unable to save website: %v", err)
+	}
+	err := database.SaveMaintenanceWindow(storage.MaintenanceWindow{
+		ID:        "1",
+		WebsiteID: "123",
+		Start:     time.Now().Add(-time.Minute),
+		End:       time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Errorf("unable to save maintenance window: %v", err)
+	}
+	recorder := &notificationRecorder{}
+
+	// action
+	checkWebsite(context.Background(), database, website, Config{Timeout: time.Second, Notifier: recorder})
+
+	// acceptance
+	if len(recorder.notifications) != 0 {
+		t.Errorf("expected no notification within maintenance window, got %#v", recorder.notifications)
+	}
+}
@@ -0,0 +1,84 @@
+package uptime
+
+import (
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+// Report availability statistics of a website within a time window
+type Report struct {
+	// Monitored total duration within the window covered by check results
+	Monitored time.Duration
+	// Complete whether check results cover the whole window, i.e. state of
+	// the website is known since the beginning of the window. Statistics of
+	// an incomplete window (e.g. history is younger than the window) only
+	// cover its monitored part
+	Complete bool
+	// Availability percentage (0-100) of monitored duration the website was
+	// healthy. It is 100 when nothing is monitored yet
+	Availability float64
+	// Downtime total duration the website was not healthy
+	Downtime time.Duration
+	// Incidents number of times the website went down
+	Incidents int
+	// MTTR mean time to recovery, average duration of an incident
+	MTTR time.Duration
+	// MTBF mean time between failures, average healthy duration between
+	// incidents
+	MTBF time.Duration
+}
+
+// Compute computes availability statistics within time range from and to
+// based on check results ordered from the oldest one. The state reported by a
+// check result is assumed to last until the next check result (or until to
+// for the latest one), and the time before the first check result is not
+// monitored. Time covered by check results within maintenance window is not
+// monitored either, so it counts neither as uptime nor as downtime
+func Compute(results []storage.CheckResult, from, to time.Time) Report {
+	var report Report
+	report.Complete = len(results) > 0 && !results[0].Time.After(from)
+	var uptime time.Duration
+	previousHealthy := true
+	for index, result := range results {
+		if !result.Time.Before(to) {
+			break
+		}
+		start := result.Time
+		end := to
+		if index+1 < len(results) && results[index+1].Time.Before(to) {
+			end = results[index+1].Time
+		}
+		if result.Maintenance {
+			continue
+		}
+		if end.Before(from) || end.Equal(from) {
+			previousHealthy = result.Healthy
+			continue
+		}
+		if start.Before(from) {
+			start = from
+		}
+		duration := end.Sub(start)
+		report.Monitored += duration
+		if result.Healthy {
+			uptime += duration
+		} else {
+			report.Downtime += duration
+			if previousHealthy {
+				report.Incidents++
+			}
+		}
+		previousHealthy = result.Healthy
+	}
+
+	report.Availability = 100
+	if report.Monitored > 0 {
+		report.Availability = float64(uptime) / float64(report.Monitored) * 100
+	}
+	if report.Incidents > 0 {
+		report.MTTR = report.Downtime / time.Duration(report.Incidents)
+		report.MTBF = uptime / time.Duration(report.Incidents)
+	}
+	return report
+}
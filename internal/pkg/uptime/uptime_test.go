@@ -0,0 +1,138 @@
+package uptime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ajiyakin/gohealthz/internal/pkg/storage"
+)
+
+var start = time.Date(2019, 3, 8, 0, 0, 0, 0, time.UTC)
+
+func checkResultAt(minute int, healthy bool) storage.CheckResult {
+	return storage.CheckResult{
+		WebsiteID: "123",
+		Time:      start.Add(time.Duration(minute) * time.Minute),
+		Healthy:   healthy,
+	}
+}
+
+func TestComputeWithIncidents(t *testing.T) {
+	// arrange
+	results := []storage.CheckResult{
+		checkResultAt(0, true),
+		checkResultAt(10, false),
+		checkResultAt(20, true),
+		checkResultAt(50, false),
+		checkResultAt(60, false),
+		checkResultAt(70, true),
+	}
+
+	// action
+	actual := Compute(results, start, start.Add(100*time.Minute))
+
+	// acceptance
+	expected := Report{
+		Monitored:    100 * time.Minute,
+		Complete:     true,
+		Availability: 70,
+		Downtime:     30 * time.Minute,
+		Incidents:    2,
+		MTTR:         15 * time.Minute,
+		MTBF:         35 * time.Minute,
+	}
+	if actual != expected {
+		t.Errorf("expected %#v got %#v", expected, actual)
+	}
+}
+
+func TestComputeExcludesMaintenance(t *testing.T) {
+	// arrange
+	maintenance := checkResultAt(20, false)
+	maintenance.Maintenance = true
+	results := []storage.CheckResult{
+		checkResultAt(0, true),
+		checkResultAt(10, false),
+		maintenance,
+		checkResultAt(50, false),
+		checkResultAt(60, true),
+	}
+
+	// action
+	actual := Compute(results, start, start.Add(100*time.Minute))
+
+	// acceptance
+	expected := Report{
+		Monitored:    70 * time.Minute,
+		Complete:     true,
+		Availability: float64(50*time.Minute) / float64(70*time.Minute) * 100,
+		Downtime:     20 * time.Minute,
+		Incidents:    1,
+		MTTR:         20 * time.Minute,
+		MTBF:         50 * time.Minute,
+	}
+	if actual != expected {
+		t.Errorf("expected %#v got %#v", expected, actual)
+	}
+}
+
+func TestComputeClipsResultsToWindow(t *testing.T) {
+	// arrange
+	results := []storage.CheckResult{
+		checkResultAt(0, false),
+		checkResultAt(30, true),
+		checkResultAt(90, false),
+	}
+
+	// action
+	actual := Compute(results, start.Add(20*time.Minute), start.Add(100*time.Minute))
+
+	// acceptance
+	expected := Report{
+		Monitored:    80 * time.Minute,
+		Complete:     true,
+		Availability: 75,
+		Downtime:     20 * time.Minute,
+		Incidents:    2,
+		MTTR:         10 * time.Minute,
+		MTBF:         30 * time.Minute,
+	}
+	if actual != expected {
+		t.Errorf("expected %#v got %#v", expected, actual)
+	}
+}
+
+func TestComputeWithoutResults(t *testing.T) {
+	// action
+	actual := Compute(nil, start, start.Add(time.Hour))
+
+	// acceptance
+	expected := Report{Availability: 100}
+	if actual != expected {
+		t.Errorf("expected %#v got %#v", expected, actual)
+	}
+}
+
+func TestComputeWithHistoryYoungerThanWindow(t *testing.T) {
+	// arrange
+	results := []storage.CheckResult{
+		checkResultAt(30, true),
+		checkResultAt(60, false),
+	}
+
+	// action
+	actual := Compute(results, start, start.Add(90*time.Minute))
+
+	// acceptance
+	expected := Report{
+		Monitored:    60 * time.Minute,
+		Availability: 50,
+		Downtime:     30 * time.Minute,
+		Incidents:    1,
+		MTTR:         30 * time.Minute,
+		MTBF:         30 * time.Minute,
+	}
+	if actual != expected {
+		t.Errorf("expected %#v got %#v", expected, actual)
+	}
+}